@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/scraper/airbnb"
+	"github.com/farhanasfar/airbnb-market-scraping-system/services"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// snapshotCalendars saves today's availability calendar of each listing and
+// returns the number of calendars saved
+func snapshotCalendars(ctx context.Context, scraper *airbnb.Scraper, calendars *services.CalendarService,
+	urls []string, logger *utils.Logger) int {
+	today := time.Now()
+	saved := 0
+	for i, url := range urls {
+		if ctx.Err() != nil {
+			logger.Warning("Interrupted, skipping the remaining calendars")
+			break
+		}
+
+		logger.Info("[%d/%d] Calendar: %s", i+1, len(urls), url)
+		days, err := scraper.ScrapeCalendar(ctx, url, today)
+		if errors.Is(err, airbnb.ErrRequestBudgetExhausted) {
+			logger.Warning("Request budget exhausted, skipping the remaining calendars")
+			break
+		}
+		if err != nil {
+			logger.Error("Failed to scrape calendar of %s: %v", url, err)
+			continue
+		}
+		if err := calendars.SaveSnapshot(url, days); err != nil {
+			logger.Error("Failed to save calendar of %s: %v", url, err)
+			continue
+		}
+		saved++
+	}
+	return saved
+}
@@ -3,28 +3,211 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config holds all configuration settings
 type Config struct {
-	Scraper  ScraperConfig  `yaml:"scraper"`
-	Database DatabaseConfig `yaml:"database"`
-	Output   OutputConfig   `yaml:"output"`
+	Scraper   ScraperConfig   `yaml:"scraper"`
+	Locations LocationsConfig `yaml:"locations"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Output    OutputConfig    `yaml:"output"`
+	Currency  CurrencyConfig  `yaml:"currency"`
 }
 
 type ScraperConfig struct {
 	BaseURL           string `yaml:"base_url"`
 	MaxPages          int    `yaml:"max_pages"`
 	PropertiesPerPage int    `yaml:"properties_per_page"`
+	CrawlMode         string `yaml:"crawl_mode"`      // "pages" (default), "all", "count" or "stale"
+	TargetListings    int    `yaml:"target_listings"` // unique listings per location in "count" mode
+	StalePages        int    `yaml:"stale_pages"`     // pages in a row with no new listings that end "stale" mode (default 2)
 	MaxWorkers        int    `yaml:"max_workers"`
 	DelayMinMs        int    `yaml:"delay_min_ms"`
 	DelayMaxMs        int    `yaml:"delay_max_ms"`
+	RequestsPerMinute int    `yaml:"requests_per_minute"` // global cap shared by all workers; 0 = no cap
+	MaxRequests       int    `yaml:"max_requests"`        // page requests allowed per run; 0 = unlimited
 	MaxRetries        int    `yaml:"max_retries"`
 	RetryDelayMs      int    `yaml:"retry_delay_ms"`
 	Headless          bool   `yaml:"headless"`
 	TimeoutSeconds    int    `yaml:"timeout_seconds"`
+	TabPoolSize       int    `yaml:"tab_pool_size"`    // browser tabs shared by all workers (default max_workers)
+	TabMaxUses        int    `yaml:"tab_max_uses"`     // pages a tab loads before it is replaced
+	SelectorProfile   string `yaml:"selector_profile"` // selector profile file; empty uses the built-in profile
+	Fetcher           string `yaml:"fetcher"`          // "chromedp" (default) or "http"
+	ReplayDir         string `yaml:"replay_dir"`       // read pages from a capture directory instead of the network
+	RecordDir         string `yaml:"record_dir"`       // save every visited page into a capture directory
+
+	// Fingerprints are browser identities rotated across sessions
+	Fingerprints []Fingerprint `yaml:"fingerprints"`
+
+	// Proxies are outbound proxy URLs (http, https or socks5) rotated across sessions
+	Proxies           []string `yaml:"proxies"`
+	ProxyMaxFailures  int      `yaml:"proxy_max_failures"`  // consecutive errors before a proxy is benched
+	ProxyBenchSeconds int      `yaml:"proxy_bench_seconds"` // how long a benched proxy sits out
+
+	// Blocks (HTTP 403/429 or a challenge page) pause the whole run, doubling the pause for each block in a row
+	BlockCooldownSeconds    int  `yaml:"block_cooldown_seconds"`
+	BlockCooldownMaxSeconds int  `yaml:"block_cooldown_max_seconds"`
+	RotateSessionOnBlock    bool `yaml:"rotate_session_on_block"` // drop the blocked session's tab, fingerprint and proxy
+
+	// APIKey is the key Airbnb's web client sends with its API requests (reviews, calendar)
+	APIKey string `yaml:"api_key"`
+
+	// Reviews are read from Airbnb's reviews endpoint, newest first
+	Reviews ReviewsConfig `yaml:"reviews"`
+
+	// Calendar snapshots record which nights each listing has open
+	Calendar CalendarConfig `yaml:"calendar"`
+
+	// Quote prices every detail page for the same stay
+	Quote QuoteConfig `yaml:"quote"`
+}
+
+// QuoteConfig is the stay detail pages are priced for, so the booking panel shows
+// the full price breakdown. Dates use the search date formats.
+type QuoteConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	CheckIn string `yaml:"check_in"` // default "+30"
+	Nights  int    `yaml:"nights"`   // default 3
+	Adults  int    `yaml:"adults"`   // default 2
+}
+
+// ReviewsConfig controls review scraping
+type ReviewsConfig struct {
+	Enabled  bool `yaml:"enabled"`   // scrape the reviews of every listing a run saves
+	PageSize int  `yaml:"page_size"` // reviews per request (default 50)
+	MaxPages int  `yaml:"max_pages"` // requests per listing; 0 = until the reviews run out
+}
+
+// CalendarConfig controls availability calendar snapshots
+type CalendarConfig struct {
+	Enabled   bool   `yaml:"enabled"`    // snapshot the calendar of every listing a run saves
+	Months    int    `yaml:"months"`     // months ahead to cover, starting this month (default 12)
+	QueryHash string `yaml:"query_hash"` // persisted query id of Airbnb's calendar API
+}
+
+// Fingerprint describes the browser a session presents to Airbnb
+type Fingerprint struct {
+	Name           string `yaml:"name"`
+	UserAgent      string `yaml:"user_agent"`
+	ViewportWidth  int    `yaml:"viewport_width"`
+	ViewportHeight int    `yaml:"viewport_height"`
+	AcceptLanguage string `yaml:"accept_language"`
+	Timezone       string `yaml:"timezone"`
+	Platform       string `yaml:"platform"`
+}
+
+// LocationsConfig chooses the locations a run scrapes
+type LocationsConfig struct {
+	Homepage bool             `yaml:"homepage"` // also scrape the locations linked from the homepage
+	Search   SearchFilters    `yaml:"search"`   // filters applied to every location
+	Sweep    SweepConfig      `yaml:"sweep"`
+	Targets  []LocationTarget `yaml:"targets"`
+}
+
+// SweepConfig repeats every location's search over a grid of check-in dates and stay lengths
+type SweepConfig struct {
+	Enabled  bool     `yaml:"enabled"`
+	Start    string   `yaml:"start"`    // first check-in, in the search date formats (default "+1", tomorrow)
+	Weeks    int      `yaml:"weeks"`    // how many weeks of check-ins to cover (default 12)
+	Weekdays []string `yaml:"weekdays"` // check-in days, e.g. ["friday", "saturday"]; empty means every day
+	Nights   []int    `yaml:"nights"`   // stay lengths (default [1])
+}
+
+// LocationTarget is one location to scrape, given as a search query or a search URL.
+// A target with only a name searches for the name.
+type LocationTarget struct {
+	Name  string `yaml:"name"`
+	Query string `yaml:"query"` // free-text search, e.g. "Lisbon, Portugal"
+	URL   string `yaml:"url"`   // full search results URL; takes precedence over query
+
+	// Search overrides the shared filters for this location; unset fields keep the shared value
+	Search SearchFilters `yaml:"search"`
+}
+
+// SearchFilters narrow a homes search to a market slice.
+// Dates are YYYY-MM-DD, "+N" for N days from today, or a weekday name for the next such day.
+type SearchFilters struct {
+	CheckIn     string   `yaml:"check_in"`
+	CheckOut    string   `yaml:"check_out"`
+	Nights      int      `yaml:"nights"` // stay length when check_out is empty
+	Adults      int      `yaml:"adults"`
+	Children    int      `yaml:"children"`
+	Infants     int      `yaml:"infants"`
+	Pets        int      `yaml:"pets"`
+	PriceMin    int      `yaml:"price_min"`
+	PriceMax    int      `yaml:"price_max"`
+	RoomType    string   `yaml:"room_type"` // entire_home, private_room, shared_room or hotel_room
+	MinBedrooms int      `yaml:"min_bedrooms"`
+	Amenities   []string `yaml:"amenities"` // names like "wifi" or "pool", or Airbnb amenity ids
+}
+
+// Merge returns f with every field set in override replaced
+func (f SearchFilters) Merge(override SearchFilters) SearchFilters {
+	if override.CheckIn != "" {
+		f.CheckIn = override.CheckIn
+		f.CheckOut = override.CheckOut
+		f.Nights = override.Nights
+	} else if override.CheckOut != "" || override.Nights != 0 {
+		f.CheckOut = override.CheckOut
+		f.Nights = override.Nights
+	}
+	if override.Adults != 0 {
+		f.Adults = override.Adults
+	}
+	if override.Children != 0 {
+		f.Children = override.Children
+	}
+	if override.Infants != 0 {
+		f.Infants = override.Infants
+	}
+	if override.Pets != 0 {
+		f.Pets = override.Pets
+	}
+	if override.PriceMin != 0 {
+		f.PriceMin = override.PriceMin
+	}
+	if override.PriceMax != 0 {
+		f.PriceMax = override.PriceMax
+	}
+	if override.RoomType != "" {
+		f.RoomType = override.RoomType
+	}
+	if override.MinBedrooms != 0 {
+		f.MinBedrooms = override.MinBedrooms
+	}
+	if override.Amenities != nil {
+		f.Amenities = override.Amenities
+	}
+	return f
+}
+
+// UseHomepage reports whether homepage discovery runs. It always does when no targets are set.
+func (l *LocationsConfig) UseHomepage() bool {
+	return l.Homepage || len(l.Targets) == 0
+}
+
+// ParseLocations parses a --locations value: search queries or URLs separated by ";".
+// The entry "homepage" adds the locations linked from the homepage.
+func ParseLocations(spec string) LocationsConfig {
+	var locations LocationsConfig
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+			continue
+		case strings.EqualFold(entry, "homepage"):
+			locations.Homepage = true
+		case strings.HasPrefix(entry, "http://") || strings.HasPrefix(entry, "https://") || strings.HasPrefix(entry, "/"):
+			locations.Targets = append(locations.Targets, LocationTarget{URL: entry})
+		default:
+			locations.Targets = append(locations.Targets, LocationTarget{Query: entry})
+		}
+	}
+	return locations
 }
 
 type DatabaseConfig struct {
@@ -41,6 +224,13 @@ type OutputConfig struct {
 	JSONConsole bool   `yaml:"json_console"`
 }
 
+// CurrencyConfig controls which currency analytics and exports report in
+type CurrencyConfig struct {
+	Default   string `yaml:"default"`    // currency of prices whose currency is unknown, e.g. "$120" on a page with no region (default "USD")
+	Target    string `yaml:"target"`     // currency reports convert to (default the default currency)
+	RatesFile string `yaml:"rates_file"` // exchange rates file; needed when prices are in several currencies
+}
+
 // Load reads and parses the config file
 func Load(filepath string) (*Config, error) {
 	data, err := os.ReadFile(filepath)
@@ -57,6 +247,11 @@ func Load(filepath string) (*Config, error) {
 	if cfg.Scraper.BaseURL == "" {
 		return nil, fmt.Errorf("scraper.url is required")
 	}
+	for i, target := range cfg.Locations.Targets {
+		if target.Name == "" && target.Query == "" && target.URL == "" {
+			return nil, fmt.Errorf("locations.targets[%d] needs a name, query or url", i)
+		}
+	}
 
 	return &cfg, nil
 }
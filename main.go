@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"flag"
-	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/farhanasfar/airbnb-market-scraping-system/config"
 	"github.com/farhanasfar/airbnb-market-scraping-system/models"
@@ -23,6 +26,18 @@ func main() {
 	topRated := flag.Bool("top-rated", false, "Show top 5 highest rated properties")
 	byLocation := flag.Bool("by-location", false, "Show listings grouped by location")
 	exportCSV := flag.Bool("export-csv", false, "Export listings to CSV file")
+	checkSelectors := flag.Bool("check-selectors", false, "Check which extraction selectors still match live pages")
+	replayDir := flag.String("replay", "", "Scrape from a directory of saved pages instead of airbnb.com")
+	recordDir := flag.String("record", "", "Save every visited page into a directory for later replay")
+	resumeRun := flag.String("resume", "", "Continue an interrupted scraping run by its run ID")
+	sweep := flag.Bool("sweep", false, "Repeat each location's search over the date grid in locations.sweep")
+	priceCurve := flag.String("price-curve", "", "Show the stay prices recorded for a listing URL")
+	quotes := flag.String("quotes", "", "Show the price breakdowns quoted for a listing URL")
+	reviewsOnly := flag.Bool("reviews", false, "Fetch new reviews of every stored listing without searching")
+	calendarOnly := flag.Bool("calendar", false, "Save today's availability calendar of every stored listing without searching")
+	occupancy := flag.Bool("occupancy", false, "Show occupancy estimated from calendar snapshots, per listing and market")
+	currency := flag.String("currency", "", "Report analytics and exports in this currency (ISO code), converting with currency.rates_file")
+	locationsFlag := flag.String("locations", "", `Locations to scrape instead of the configured ones: search queries or URLs separated by ";" ("homepage" adds the homepage locations)`)
 
 	flag.Parse()
 
@@ -33,6 +48,41 @@ func main() {
 	if err != nil {
 		log.Fatal("Failed to load config:", err)
 	}
+	if *replayDir != "" {
+		cfg.Scraper.ReplayDir = *replayDir
+	}
+	if *recordDir != "" {
+		cfg.Scraper.RecordDir = *recordDir
+	}
+	if *sweep {
+		cfg.Locations.Sweep.Enabled = true
+	}
+	if *currency != "" {
+		cfg.Currency.Target = *currency
+	}
+	if *locationsFlag != "" {
+		if *resumeRun != "" {
+			logger.Warning("--locations is ignored when resuming; the run keeps its original locations")
+		} else {
+			search, sweep := cfg.Locations.Search, cfg.Locations.Sweep
+			cfg.Locations = config.ParseLocations(*locationsFlag)
+			cfg.Locations.Search, cfg.Locations.Sweep = search, sweep
+		}
+	}
+
+	// Ctrl-C or SIGTERM cancels the run; a second signal kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	// Selector health check needs no database
+	if *checkSelectors {
+		runSelectorCheck(ctx, cfg, logger)
+		return
+	}
 
 	// Connect to database
 	db, err := storage.NewDB(cfg.Database.GetDSN())
@@ -42,8 +92,9 @@ func main() {
 	defer db.Close()
 
 	// Create services
-	analyticsService := services.NewAnalyticsService(db, logger)
-	csvService := services.NewCSVService(db, logger)
+	converter := newCurrencyConverter(cfg)
+	analyticsService := services.NewAnalyticsService(db, logger, converter)
+	csvService := services.NewCSVService(db, logger, converter)
 
 	// Handle analytics flags (no scraping needed)
 	if *showStats {
@@ -83,6 +134,27 @@ func main() {
 		return
 	}
 
+	if *priceCurve != "" {
+		if err := analyticsService.PrintPriceCurve(*priceCurve); err != nil {
+			log.Fatal("Failed to get price curve:", err)
+		}
+		return
+	}
+
+	if *quotes != "" {
+		if err := analyticsService.PrintQuotes(*quotes); err != nil {
+			log.Fatal("Failed to get price quotes:", err)
+		}
+		return
+	}
+
+	if *occupancy {
+		if err := analyticsService.PrintOccupancy(); err != nil {
+			log.Fatal("Failed to estimate occupancy:", err)
+		}
+		return
+	}
+
 	if *exportCSV {
 		if err := csvService.ExportToCSV(cfg.Output.CSVFile); err != nil {
 			log.Fatal("Failed to export CSV:", err)
@@ -90,29 +162,197 @@ func main() {
 		return
 	}
 
+	if *reviewsOnly || *calendarOnly {
+		runListingUpdates(ctx, cfg, db, logger, *reviewsOnly, *calendarOnly)
+		return
+	}
+
 	// No flags = run scraping (default behavior)
-	runScraping(cfg, db, logger)
+	runScraping(ctx, cfg, db, logger, *resumeRun)
+}
+
+// runSelectorCheck reports which selectors still match and exits non-zero
+// when a required field stops matching
+func runSelectorCheck(ctx context.Context, cfg *config.Config, logger *utils.Logger) {
+	scraper, err := airbnb.NewScraper(&cfg.Scraper, logger)
+	if err != nil {
+		log.Fatal("Failed to create scraper:", err)
+	}
+	defer scraper.Close()
+
+	report, err := scraper.CheckSelectors(ctx)
+	if err != nil {
+		scraper.Close()
+		log.Fatal("Selector check failed:", err)
+	}
+
+	report.Print(logger)
+	if len(report.Failed()) > 0 {
+		scraper.Close()
+		os.Exit(1)
+	}
+}
+
+// runListingUpdates fetches new reviews and/or today's calendar for every stored listing
+func runListingUpdates(ctx context.Context, cfg *config.Config, db *storage.DB, logger *utils.Logger, reviews, calendar bool) {
+	listings, err := services.NewListingService(db, logger).GetAllListings()
+	if err != nil {
+		log.Fatal("Failed to load listings:", err)
+	}
+	if len(listings) == 0 {
+		logger.Warning("No listings stored, run the scraper first")
+		return
+	}
+
+	scraper, err := airbnb.NewScraper(&cfg.Scraper, logger)
+	if err != nil {
+		log.Fatal("Failed to create scraper:", err)
+	}
+	defer scraper.Close()
+
+	urls := make([]string, len(listings))
+	for i, listing := range listings {
+		urls[i] = listing.URL
+	}
+
+	if reviews {
+		logger.Info("Fetching new reviews of %d listings...", len(urls))
+		saved := scrapeReviews(ctx, scraper, services.NewReviewService(db, logger), urls, logger)
+		logger.Success("Saved %d reviews", saved)
+	}
+	if calendar && ctx.Err() == nil {
+		logger.Info("Saving the calendars of %d listings...", len(urls))
+		saved := snapshotCalendars(ctx, scraper, services.NewCalendarService(db, logger), urls, logger)
+		logger.Success("Saved %d calendar snapshots", saved)
+	}
+	logger.Info("Page requests made: %d", scraper.RequestCount())
+}
+
+// newCurrencyConverter reports in the configured target currency, loading the
+// exchange rates file when one is set
+func newCurrencyConverter(cfg *config.Config) *utils.CurrencyConverter {
+	var rates *utils.ExchangeRates
+	if cfg.Currency.RatesFile != "" {
+		loaded, err := utils.LoadExchangeRates(cfg.Currency.RatesFile)
+		if err != nil {
+			log.Fatal("Failed to load exchange rates:", err)
+		}
+		rates = loaded
+	}
+	return utils.NewCurrencyConverter(cfg.Currency.Target, cfg.Currency.Default, rates)
 }
 
-func runScraping(cfg *config.Config, db *storage.DB, logger *utils.Logger) {
+// runScraping scrapes, saves and exports listings.
+// When ctx is cancelled it stops scraping and saves whatever it has so far.
+// A non-empty resumeID continues that run from its checkpoints instead of starting over.
+func runScraping(ctx context.Context, cfg *config.Config, db *storage.DB, logger *utils.Logger, resumeID string) {
 	logger.Info("Starting Airbnb Multi-Location Scraper...")
 
 	// Create services
 	listingService := services.NewListingService(db, logger)
-	csvService := services.NewCSVService(db, logger)
-	analyticsService := services.NewAnalyticsService(db, logger)
-	scraper := airbnb.NewScraper(&cfg.Scraper, logger)
-	ctx := context.Background()
-
-	// Step 1: Scrape homepage to get location URLs
-	logger.Info("\n=== STEP 1: EXTRACTING LOCATIONS FROM HOMEPAGE ===")
-	locations, err := scraper.ScrapeHomepageLocations(ctx)
+	converter := newCurrencyConverter(cfg)
+	csvService := services.NewCSVService(db, logger, converter)
+	analyticsService := services.NewAnalyticsService(db, logger, converter)
+	scraper, err := airbnb.NewScraper(&cfg.Scraper, logger)
 	if err != nil {
-		log.Fatal("Failed to scrape homepage:", err)
+		log.Fatal("Failed to create scraper:", err)
+	}
+	defer scraper.Close()
+
+	// Every page and detail result is checkpointed so the run can be resumed
+	checkpoints := services.NewCheckpointService(db, logger)
+	var run *models.CrawlRun
+	var locations []models.CrawlLocation
+	var pending []models.RawListing
+
+	if resumeID != "" {
+		run, err = checkpoints.ResumeRun(resumeID)
+		if err != nil {
+			scraper.Close()
+			log.Fatal("Failed to resume run:", err)
+		}
+		locations, err = checkpoints.Locations(run.ID)
+		if err != nil {
+			scraper.Close()
+			log.Fatal("Failed to load checkpointed locations:", err)
+		}
+		pending, err = checkpoints.PendingListings(run.ID)
+		if err != nil {
+			scraper.Close()
+			log.Fatal("Failed to load checkpointed listings:", err)
+		}
+		logger.Info("Loaded %d locations and %d listings still missing details from run %s",
+			len(locations), len(pending), run.ID)
+	} else {
+		run, err = checkpoints.StartRun()
+		if err != nil {
+			scraper.Close()
+			log.Fatal("Failed to start run:", err)
+		}
+	}
+	logger.Info("Run ID: %s (continue an interrupted run with --resume %s)", run.ID, run.ID)
+
+	if len(locations) == 0 {
+		// Step 1: Collect the configured locations, plus the homepage ones if enabled
+		logger.Info("\n=== STEP 1: COLLECTING LOCATIONS ===")
+		today := time.Now()
+		cards, err := airbnb.TargetLocations(cfg.Scraper.BaseURL, cfg.Locations, today)
+		if err != nil {
+			scraper.Close()
+			log.Fatal("Invalid locations:", err)
+		}
+
+		if cfg.Locations.UseHomepage() {
+			discovered, err := scraper.ScrapeHomepageLocations(ctx)
+			if ctx.Err() != nil {
+				logger.Warning("Interrupted before any listings were scraped")
+				return
+			}
+			if err != nil {
+				if len(cards) == 0 {
+					scraper.Close()
+					log.Fatal("Failed to scrape homepage:", err)
+				}
+				logger.Error("Homepage discovery failed, scraping the configured locations only: %v", err)
+			}
+			discovered, err = airbnb.FilterLocations(discovered, cfg.Locations.Search, today)
+			if err != nil {
+				scraper.Close()
+				log.Fatal("Invalid search filters:", err)
+			}
+			cards = airbnb.MergeLocations(cards, discovered)
+		}
+
+		if cfg.Locations.Sweep.Enabled {
+			stays, err := airbnb.SweepDates(cfg.Locations.Sweep, today)
+			if err != nil {
+				scraper.Close()
+				log.Fatal("Invalid sweep:", err)
+			}
+			places := len(cards)
+			if cards, err = airbnb.SweepLocations(cards, stays); err != nil {
+				scraper.Close()
+				log.Fatal("Invalid sweep:", err)
+			}
+			logger.Info("Date sweep: %d locations × %d stays = %d searches (up to %d search pages)",
+				places, len(stays), len(cards), len(cards)*cfg.Scraper.MaxPages)
+			if budget := cfg.Scraper.MaxRequests; budget > 0 && len(cards) > budget {
+				logger.Warning("The sweep needs more page requests than max_requests (%d) allows; later stays will be skipped", budget)
+			}
+		}
+
+		for _, card := range cards {
+			locations = append(locations, models.CrawlLocation{Name: card.Name, URL: card.URL})
+		}
+		locations, err = checkpoints.SaveLocations(run.ID, locations)
+		if err != nil {
+			scraper.Close()
+			log.Fatal("Failed to checkpoint locations:", err)
+		}
 	}
 
 	if len(locations) == 0 {
-		logger.Warning("No locations found on homepage")
+		logger.Warning("No locations to scrape")
 		return
 	}
 
@@ -121,21 +361,68 @@ func runScraping(cfg *config.Config, db *storage.DB, logger *utils.Logger) {
 		logger.Info("  %d. %s", i+1, loc.Name)
 	}
 
-	// Step 2: Scrape properties from each location
+	// Step 2: Scrape properties from each location.
+	// Listings are saved as each results page is parsed and their detail pages
+	// are scraped alongside the search.
 	logger.Info("\n=== STEP 2: SCRAPING PROPERTIES FROM EACH LOCATION ===")
 
-	allRawListings := []models.RawListing{}
-	totalProperties := 0
+	pipeline := newListingPipeline(run.ID, listingService, checkpoints, logger, cfg.Output.JSONConsole)
+	pipeline.start(ctx, scraper)
+	if len(pending) > 0 {
+		pipeline.add(pending)
+	}
 
 	for i, location := range locations {
+		if location.Done {
+			logger.Info("\n[%d/%d] Already scraped: %s", i+1, len(locations), location.Name)
+			continue
+		}
 		logger.Info("\n[%d/%d] Scraping: %s", i+1, len(locations), location.Name)
 
-		// Scrape this location (2 pages × 5 properties = 10 per location)
-		rawListings, err := scraper.ScrapeListings(ctx, location.URL)
+		// Continue after the last checkpointed page
+		cursor := airbnb.SearchCursor{URL: location.URL, Page: 1}
+		if location.NextURL != "" {
+			cursor = airbnb.SearchCursor{
+				URL:   location.NextURL,
+				Page:  location.PagesDone + 1,
+				Found: location.Found,
+				Stale: location.StalePages,
+			}
+		}
+
+		// Scrape this location as far as the crawl mode asks
+		rawListings, err := scraper.ScrapeListingsFrom(ctx, cursor,
+			func(page int, listings []models.RawListing, next *airbnb.SearchCursor) error {
+				progress := location
+				progress.PagesDone = page
+				progress.NextURL = ""
+				if next != nil {
+					progress.NextURL = next.URL
+					progress.Found = next.Found
+					progress.StalePages = next.Stale
+				}
+				if err := checkpoints.SavePage(run.ID, progress, listings); err != nil {
+					return err
+				}
+				pipeline.add(listings)
+				return nil
+			})
+		if ctx.Err() != nil {
+			logger.Warning("Interrupted, skipping remaining locations")
+			break
+		}
+		if errors.Is(err, airbnb.ErrRequestBudgetExhausted) {
+			logger.Warning("Request budget exhausted, skipping remaining locations")
+			break
+		}
 		if err != nil {
+			// Pages scraped before the failure are saved; --resume reloads only the failed page
 			logger.Error("Failed to scrape %s: %v", location.Name, err)
 			continue
 		}
+		if err := checkpoints.FinishLocation(run.ID, location); err != nil {
+			logger.Warning("Failed to checkpoint %s: %v", location.Name, err)
+		}
 
 		if len(rawListings) == 0 {
 			logger.Warning("No listings found for %s", location.Name)
@@ -143,67 +430,50 @@ func runScraping(cfg *config.Config, db *storage.DB, logger *utils.Logger) {
 		}
 
 		logger.Success("Got %d properties from %s", len(rawListings), location.Name)
-		allRawListings = append(allRawListings, rawListings...)
-		totalProperties += len(rawListings)
 	}
 
-	logger.Success("\n=== SCRAPED %d TOTAL PROPERTIES FROM %d LOCATIONS ===",
-		totalProperties, len(locations))
+	// Step 3: Finish the detail pages still queued
+	logger.Info("\n=== STEP 3: FINISHING DETAIL PAGES ===")
+	pipeline.wait()
 
-	if totalProperties == 0 {
-		logger.Warning("No properties scraped, exiting")
-		return
+	reviewsSaved := 0
+	if cfg.Scraper.Reviews.Enabled && ctx.Err() == nil {
+		logger.Info("\n=== STEP 3b: SCRAPING REVIEWS ===")
+		reviewsSaved = scrapeReviews(ctx, scraper, services.NewReviewService(db, logger), pipeline.urls(), logger)
 	}
 
-	// Print preview if JSON console enabled
-	if cfg.Output.JSONConsole {
-		logger.Info("\n=== PREVIEW (first 2 listings) ===")
-		preview := allRawListings
-		if len(preview) > 2 {
-			preview = preview[:2]
-		}
-		jsonData, _ := json.MarshalIndent(preview, "", "  ")
-		fmt.Println(string(jsonData))
+	calendarsSaved := 0
+	if cfg.Scraper.Calendar.Enabled && ctx.Err() == nil {
+		logger.Info("\n=== STEP 3c: SAVING AVAILABILITY CALENDARS ===")
+		calendarsSaved = snapshotCalendars(ctx, scraper, services.NewCalendarService(db, logger), pipeline.urls(), logger)
 	}
 
-	// Step 3: Scrape detail pages
-	logger.Info("\n=== STEP 3: SCRAPING DETAIL PAGES ===")
-	urls := make([]string, 0, len(allRawListings))
-	for _, listing := range allRawListings {
-		if listing.URL != "" {
-			normalizedURL := utils.NormalizeURL(listing.URL)
-			urls = append(urls, normalizedURL)
-		}
-	}
+	// Scraping is over; shut Chrome down before exporting
+	scraper.Close()
 
-	logger.Info("Scraping details for %d properties...", len(urls))
-	detailResults := scraper.ScrapeDetailsWithWorkers(ctx, urls)
+	logger.Success("\n=== SCRAPED %d TOTAL PROPERTIES FROM %d LOCATIONS ===",
+		pipeline.total, len(locations))
 
-	// Merge detail data
-	for i := range allRawListings {
-		normalizedURL := utils.NormalizeURL(allRawListings[i].URL)
-		if detail, ok := detailResults[normalizedURL]; ok && detail.Error == nil {
-			allRawListings[i].Bedrooms = detail.Bedrooms
-			allRawListings[i].Bathrooms = detail.Bathrooms
-			allRawListings[i].Guests = detail.Guests
+	completed := false
+	if ctx.Err() == nil {
+		if completed, err = checkpoints.CompleteRun(run.ID); err != nil {
+			logger.Warning("Failed to mark run %s completed: %v", run.ID, err)
 		}
 	}
 
-	// Step 4: Save to database
-	logger.Info("\n=== STEP 4: SAVING TO DATABASE ===")
-	savedCount, err := listingService.NormalizeAndSave(allRawListings)
-	if err != nil {
-		logger.Error("Failed to save listings: %v", err)
+	if pipeline.total == 0 {
+		logger.Warning("No properties scraped, exiting")
+		return
 	}
 
-	// Step 5: Export to CSV
-	logger.Info("\n=== STEP 5: EXPORTING TO CSV ===")
+	// Step 4: Export to CSV
+	logger.Info("\n=== STEP 4: EXPORTING TO CSV ===")
 	if err := csvService.ExportToCSV(cfg.Output.CSVFile); err != nil {
 		logger.Error("Failed to export CSV: %v", err)
 	}
 
-	// Step 6: Show analytics
-	logger.Info("\n=== STEP 6: ANALYTICS SUMMARY ===")
+	// Step 5: Show analytics
+	logger.Info("\n=== STEP 5: ANALYTICS SUMMARY ===")
 	analytics, err := analyticsService.GetAnalytics()
 	if err != nil {
 		logger.Error("Failed to calculate analytics: %v", err)
@@ -212,11 +482,39 @@ func runScraping(cfg *config.Config, db *storage.DB, logger *utils.Logger) {
 	}
 
 	// Final summary
-	logger.Success("\n=== SCRAPING COMPLETE ===")
+	if ctx.Err() != nil {
+		logger.Warning("\n=== SCRAPING INTERRUPTED, PARTIAL RESULTS SAVED ===")
+		logger.Info("Resume with: go run . --resume %s", run.ID)
+	} else if !completed {
+		logger.Warning("\n=== SCRAPING INCOMPLETE, PARTIAL RESULTS SAVED ===")
+		logger.Info("Resume with: go run . --resume %s", run.ID)
+	} else {
+		logger.Success("\n=== SCRAPING COMPLETE ===")
+	}
 	logger.Info("Locations scraped: %d", len(locations))
-	logger.Info("Total properties found: %d", totalProperties)
-	logger.Info("Successfully saved: %d", savedCount)
+	logger.Info("Total properties found: %d", pipeline.total)
+	logger.Info("Successfully saved: %d", pipeline.saved)
+	logger.Info("Detail pages merged: %d", pipeline.detailed)
+	if cfg.Scraper.Reviews.Enabled {
+		logger.Info("Reviews saved: %d", reviewsSaved)
+	}
+	if cfg.Scraper.Calendar.Enabled {
+		logger.Info("Calendars saved: %d", calendarsSaved)
+	}
+	logger.Info("Page requests made: %d", scraper.RequestCount())
+	pageCounts := scraper.PageCounts()
+	logger.Info("Page loads by outcome:")
+	for _, class := range airbnb.PageClasses {
+		logger.Info("  %s: %d", class, pageCounts[class])
+	}
+	for _, ps := range scraper.ProxyStats() {
+		status := ""
+		if ps.Benched {
+			status = " (benched)"
+		}
+		logger.Info("Proxy %s: %d requests, %d errors%s", ps.Proxy, ps.Uses, ps.Errors, status)
+	}
 	logger.Info("CSV file: %s", cfg.Output.CSVFile)
 	logger.Info("\n💡 Tip: Run with --show-stats to see analytics anytime!")
-	logger.Info("   Other flags: --avg-price, --max-price, --top-rated, --by-location, --export-csv")
+	logger.Info("   Other flags: --avg-price, --max-price, --top-rated, --by-location, --export-csv, --check-selectors, --record, --replay, --resume, --locations, --sweep, --price-curve, --quotes, --currency, --reviews, --calendar, --occupancy")
 }
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// CalendarDay is one night of a listing's availability calendar as seen on SnapshotDate
+type CalendarDay struct {
+	ID           int       `json:"id" db:"id"`
+	URL          string    `json:"url" db:"url"`
+	SnapshotDate time.Time `json:"snapshot_date" db:"snapshot_date"`
+	Date         time.Time `json:"date" db:"date"`
+	Available    bool      `json:"available" db:"available"`
+	CheckIn      bool      `json:"check_in" db:"check_in"`   // a stay can start this night
+	CheckOut     bool      `json:"check_out" db:"check_out"` // a stay can end this morning
+	MinNights    int       `json:"min_nights" db:"min_nights"`
+	MaxNights    int       `json:"max_nights" db:"max_nights"`
+}
+
+// Occupancy is the booking activity inferred from a listing's calendar snapshots
+type Occupancy struct {
+	URL           string
+	City          string
+	Snapshots     int
+	From          time.Time // first snapshot
+	To            time.Time // last snapshot
+	TrackedNights int       // future nights seen open in one snapshot and seen again in the next
+	BookedNights  int       // of those, nights that closed, likely booked
+	BlockedNights int       // nights that closed in long runs, likely blocked by the host
+}
+
+// Rate returns the share of tracked nights that were booked, leaving out the
+// nights the host blocked since they were no longer for rent
+func (o Occupancy) Rate() float64 {
+	bookable := o.TrackedNights - o.BlockedNights
+	if bookable <= 0 {
+		return 0
+	}
+	return float64(o.BookedNights) / float64(bookable)
+}
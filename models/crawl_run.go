@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Crawl run statuses
+const (
+	CrawlRunning   = "running"
+	CrawlCompleted = "completed"
+	CrawlPartial   = "partial" // ended with locations or detail pages left; can be resumed
+)
+
+// CrawlRun is a scraping run whose progress is checkpointed so it can be resumed
+type CrawlRun struct {
+	ID        string    `json:"id" db:"id"`
+	Status    string    `json:"status" db:"status"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CrawlLocation is a location discovered by a run and how far its results have been scraped
+type CrawlLocation struct {
+	Position   int    `json:"position" db:"position"`
+	Name       string `json:"name" db:"name"`
+	URL        string `json:"url" db:"url"`
+	PagesDone  int    `json:"pages_done" db:"pages_done"`
+	NextURL    string `json:"next_url" db:"next_url"`       // results page to continue from; empty before the first page
+	Found      int    `json:"found" db:"found"`             // unique listings scraped so far
+	StalePages int    `json:"stale_pages" db:"stale_pages"` // latest pages in a row with no new listings
+	Done       bool   `json:"done" db:"done"`
+}
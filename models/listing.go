@@ -4,27 +4,58 @@ import "time"
 
 // Airbnb property listing
 type Listing struct {
-	ID        int       `json:"id" db:"id"`
-	Title     string    `json:"title" db:"title"`
-	Price     float64   `json:"price" db:"price"`
-	Location  string    `json:"location" db:"location"`
-	Rating    float64   `json:"rating" db:"rating"`
-	URL       string    `json:"url" db:"url"`
-	Bedrooms  int       `json:"bedrooms" db:"bedrooms"`
-	Bathrooms int       `json:"bathrooms" db:"bathrooms"`
-	Guests    int       `json:"guests" db:"guests"`
+	ID           int               `json:"id" db:"id"`
+	Title        string            `json:"title" db:"title"`
+	Price        float64           `json:"price" db:"price"`
+	Currency     string            `json:"currency" db:"currency"` // ISO code, "" when the price did not name one
+	Location     string            `json:"location" db:"location"`
+	Rating       float64           `json:"rating" db:"rating"`
+	URL          string            `json:"url" db:"url"`
+	Bedrooms     int               `json:"bedrooms" db:"bedrooms"`
+	Bathrooms    int               `json:"bathrooms" db:"bathrooms"`
+	Guests       int               `json:"guests" db:"guests"`
+	Latitude     float64           `json:"latitude" db:"latitude"` // approximate, Airbnb offsets the pin
+	Longitude    float64           `json:"longitude" db:"longitude"`
+	Neighborhood string            `json:"neighborhood" db:"neighborhood"`
+	City         string            `json:"city" db:"city"`
+	Region       string            `json:"region" db:"region"`
+	Country      string            `json:"country" db:"country"`
+	SearchParams map[string]string `json:"search_params,omitempty" db:"search_params"` // search URL parameters it was found with
+	ListingDetails
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // structure before normalization
 type RawListing struct {
-	Title     string
-	Price     string
-	Location  string
-	Rating    string
-	URL       string
-	Bedrooms  int
-	Bathrooms int
-	Guests    int
+	RoomID      string
+	Title       string
+	Price       string
+	Location    string
+	Rating      string
+	URL         string
+	Bedrooms    int
+	Bathrooms   int
+	Guests      int
+	ReviewCount int
+	Latitude    float64
+	Longitude   float64
+
+	Neighborhood string
+	City         string
+	Region       string
+	Country      string
+
+	SearchParams map[string]string
+
+	// Locale the page wrote the price and rating in, e.g. "de-DE"
+	Locale string
+
+	// ISO code of the currency the page's embedded state priced in; "" when it named none
+	Currency string
+
+	// Price breakdown shown with a dated search result; nil when the search showed none
+	Quote *PriceQuote
+
+	ListingDetails
 }
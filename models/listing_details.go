@@ -0,0 +1,21 @@
+package models
+
+// ListingDetails are the fields only a listing's detail page shows
+type ListingDetails struct {
+	PropertyType string   `json:"property_type,omitempty" db:"property_type"` // e.g. "Entire rental unit"
+	RoomType     string   `json:"room_type,omitempty" db:"room_type"`         // e.g. "Entire home/apt"
+	Beds         int      `json:"beds,omitempty" db:"beds"`
+	Amenities    []string `json:"amenities,omitempty" db:"amenities"`
+
+	HostName         string `json:"host_name,omitempty" db:"host_name"`
+	HostID           string `json:"host_id,omitempty" db:"host_id"`
+	Superhost        bool   `json:"superhost,omitempty" db:"superhost"`
+	HostJoinYear     int    `json:"host_join_year,omitempty" db:"host_join_year"`
+	HostResponseRate int    `json:"host_response_rate,omitempty" db:"host_response_rate"` // percent
+
+	CheckInTime        string `json:"check_in_time,omitempty" db:"check_in_time"`
+	CheckOutTime       string `json:"check_out_time,omitempty" db:"check_out_time"`
+	MinNights          int    `json:"min_nights,omitempty" db:"min_nights"`
+	CancellationPolicy string `json:"cancellation_policy,omitempty" db:"cancellation_policy"`
+	InstantBook        bool   `json:"instant_book,omitempty" db:"instant_book"`
+}
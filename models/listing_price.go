@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// ListingPrice is the price a listing showed in a search for one stay
+type ListingPrice struct {
+	ID           int       `json:"id" db:"id"`
+	URL          string    `json:"url" db:"url"`
+	CheckIn      time.Time `json:"check_in" db:"check_in"`
+	CheckOut     time.Time `json:"check_out" db:"check_out"`
+	Nights       int       `json:"nights" db:"nights"`
+	Price        float64   `json:"price" db:"price"`                 // amount shown on the search card
+	NightlyPrice float64   `json:"nightly_price" db:"nightly_price"` // price per night of the stay
+	Currency     string    `json:"currency" db:"currency"`
+	ObservedAt   time.Time `json:"observed_at" db:"observed_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// PriceQuote is the price breakdown a listing's booking panel showed for a fixed stay
+type PriceQuote struct {
+	ID              int       `json:"id" db:"id"`
+	URL             string    `json:"url" db:"url"`
+	CheckIn         time.Time `json:"check_in" db:"check_in"`
+	CheckOut        time.Time `json:"check_out" db:"check_out"`
+	Guests          int       `json:"guests" db:"guests"`
+	Nights          int       `json:"nights" db:"nights"`
+	NightlyRate     float64   `json:"nightly_rate" db:"nightly_rate"`         // before discounts
+	OriginalPrice   float64   `json:"original_price" db:"original_price"`     // nights × nightly rate
+	DiscountedPrice float64   `json:"discounted_price" db:"discounted_price"` // original price less discounts
+	CleaningFee     float64   `json:"cleaning_fee" db:"cleaning_fee"`
+	ServiceFee      float64   `json:"service_fee" db:"service_fee"`
+	Taxes           float64   `json:"taxes" db:"taxes"`
+	Total           float64   `json:"total" db:"total"`
+	Currency        string    `json:"currency" db:"currency"`
+	ObservedAt      time.Time `json:"observed_at" db:"observed_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Review is one guest review of a listing
+type Review struct {
+	ID           int       `json:"id" db:"id"`
+	URL          string    `json:"url" db:"url"` // normalized listing URL
+	ReviewID     string    `json:"review_id" db:"review_id"`
+	ReviewerName string    `json:"reviewer_name" db:"reviewer_name"` // first name only
+	ReviewedAt   time.Time `json:"reviewed_at" db:"reviewed_at"`
+	Language     string    `json:"language" db:"language"`
+	Text         string    `json:"text" db:"text"`
+	HostResponse string    `json:"host_response" db:"host_response"`
+	ScrapedAt    time.Time `json:"scraped_at" db:"scraped_at"`
+}
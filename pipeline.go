@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/models"
+	"github.com/farhanasfar/airbnb-market-scraping-system/scraper/airbnb"
+	"github.com/farhanasfar/airbnb-market-scraping-system/services"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// listingPipeline saves listings while the crawl is still running.
+// Each search page is saved as soon as it is parsed, its listings are queued
+// for the detail workers, and every detail result is patched into the saved
+// listing. Only listings still waiting for their detail page stay in memory.
+type listingPipeline struct {
+	runID       string
+	listings    *services.ListingService
+	checkpoints *services.CheckpointService
+	logger      *utils.Logger
+	preview     bool // print the first listings as JSON
+
+	found chan []models.RawListing
+	done  chan struct{}
+
+	// Owned by the run goroutine
+	pending   map[string]models.RawListing // saved listings waiting for details, by normalized URL
+	seen      map[string]bool
+	queue     []string // detail pages not yet handed to a worker
+	total     int
+	saved     int
+	detailed  int
+	previewed bool
+}
+
+func newListingPipeline(runID string, listings *services.ListingService, checkpoints *services.CheckpointService,
+	logger *utils.Logger, preview bool) *listingPipeline {
+	return &listingPipeline{
+		runID:       runID,
+		listings:    listings,
+		checkpoints: checkpoints,
+		logger:      logger,
+		preview:     preview,
+		found:       make(chan []models.RawListing),
+		done:        make(chan struct{}),
+		pending:     make(map[string]models.RawListing),
+		seen:        make(map[string]bool),
+	}
+}
+
+// start launches the detail workers and the goroutine that saves listings
+func (p *listingPipeline) start(ctx context.Context, scraper *airbnb.Scraper) {
+	urls := make(chan string)
+	results := scraper.ScrapeDetailsStream(ctx, urls)
+	go p.run(urls, results)
+}
+
+// add hands a page of scraped listings to the pipeline
+func (p *listingPipeline) add(listings []models.RawListing) {
+	p.found <- listings
+}
+
+// wait tells the pipeline no more listings are coming and waits for the
+// outstanding detail pages. After a cancellation it returns once the workers stop.
+func (p *listingPipeline) wait() {
+	close(p.found)
+	<-p.done
+}
+
+func (p *listingPipeline) run(urls chan<- string, results <-chan *airbnb.DetailResult) {
+	defer close(p.done)
+
+	found := p.found
+	urlsOpen := true
+	for found != nil || results != nil {
+		// Offer the next detail page only while workers are still taking them
+		var send chan<- string
+		var next string
+		if results != nil && urlsOpen && len(p.queue) > 0 {
+			send = urls
+			next = p.queue[0]
+		}
+
+		select {
+		case listings, ok := <-found:
+			if !ok {
+				found = nil
+				break
+			}
+			p.save(listings)
+		case send <- next:
+			p.queue = p.queue[1:]
+		case result, ok := <-results:
+			if !ok {
+				results = nil
+				break
+			}
+			p.patch(result)
+		}
+
+		// The search is over and every detail page is handed out
+		if found == nil && len(p.queue) == 0 && urlsOpen {
+			close(urls)
+			urlsOpen = false
+		}
+	}
+}
+
+// urls returns the normalized URLs of every listing the run saved, sorted.
+// Call it only after wait.
+func (p *listingPipeline) urls() []string {
+	urls := make([]string, 0, len(p.seen))
+	for url := range p.seen {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// save stores listings not seen before in this run and queues their detail pages.
+// Stay prices are recorded for every listing, including repeats, since a date
+// sweep finds the same listing once per stay.
+func (p *listingPipeline) save(listings []models.RawListing) {
+	p.saveNew(listings)
+	p.listings.SavePrices(listings)
+}
+
+func (p *listingPipeline) saveNew(listings []models.RawListing) {
+	fresh := make([]models.RawListing, 0, len(listings))
+	for _, listing := range listings {
+		url := utils.NormalizeURL(listing.URL)
+		if url == "" || p.seen[url] {
+			continue // already saved and queued for its detail page in this run
+		}
+		p.seen[url] = true
+		fresh = append(fresh, listing)
+	}
+	if len(fresh) == 0 {
+		return
+	}
+	p.total += len(fresh)
+
+	if p.preview && !p.previewed {
+		p.previewed = true
+		preview := fresh
+		if len(preview) > 2 {
+			preview = preview[:2]
+		}
+		p.logger.Info("\n=== PREVIEW (first 2 listings) ===")
+		jsonData, _ := json.MarshalIndent(preview, "", "  ")
+		fmt.Println(string(jsonData))
+	}
+
+	count, err := p.listings.NormalizeAndSave(fresh)
+	if err != nil {
+		p.logger.Error("Failed to save listings: %v", err)
+		return
+	}
+	p.saved += count
+
+	for _, listing := range fresh {
+		url := utils.NormalizeURL(listing.URL)
+		p.pending[url] = listing
+		p.queue = append(p.queue, url)
+	}
+}
+
+// patch merges a detail result into its saved listing and checkpoints it
+func (p *listingPipeline) patch(detail *airbnb.DetailResult) {
+	listing, ok := p.pending[detail.URL]
+	delete(p.pending, detail.URL)
+	if !ok || detail.Error != nil {
+		return
+	}
+
+	applyDetail(&listing, detail)
+	if err := p.listings.SaveDetails(listing); err != nil {
+		p.logger.Error("Failed to save details of %s: %v", detail.URL, err)
+		return
+	}
+	p.detailed++
+
+	if detail.Quote != nil {
+		if err := p.listings.SaveQuote(detail.Quote); err != nil {
+			p.logger.Warning("Failed to save price quote of %s: %v", detail.URL, err)
+		}
+	}
+
+	if err := p.checkpoints.SaveDetail(p.runID, listing); err != nil {
+		p.logger.Warning("Failed to checkpoint details of %s: %v", detail.URL, err)
+	}
+}
+
+// applyDetail copies the fields scraped from a detail page into its listing
+func applyDetail(listing *models.RawListing, detail *airbnb.DetailResult) {
+	listing.Bedrooms = detail.Bedrooms
+	listing.Bathrooms = detail.Bathrooms
+	listing.Guests = detail.Guests
+	if listing.Latitude == 0 && listing.Longitude == 0 {
+		listing.Latitude = detail.Latitude
+		listing.Longitude = detail.Longitude
+	}
+	if detail.City != "" {
+		listing.Neighborhood = detail.Neighborhood
+		listing.City = detail.City
+		listing.Region = detail.Region
+		listing.Country = detail.Country
+	}
+	// Card titles such as "Loft in Richmond" name the neighborhood when it is not the city
+	if place := airbnb.PlaceFromTitle(listing.Location); listing.Neighborhood == "" && place != "" && place != listing.City {
+		listing.Neighborhood = place
+	}
+	if listing.Rating == "" && detail.Rating > 0 {
+		listing.Rating = strconv.FormatFloat(detail.Rating, 'f', 2, 64)
+	}
+	if detail.ReviewCount > 0 {
+		listing.ReviewCount = detail.ReviewCount
+	}
+	listing.ListingDetails = detail.ListingDetails
+}
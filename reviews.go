@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/scraper/airbnb"
+	"github.com/farhanasfar/airbnb-market-scraping-system/services"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// scrapeReviews fetches the reviews of each listing posted since its newest stored
+// review and saves them. It returns the number of reviews saved.
+func scrapeReviews(ctx context.Context, scraper *airbnb.Scraper, reviews *services.ReviewService,
+	urls []string, logger *utils.Logger) int {
+	saved := 0
+	for i, url := range urls {
+		if ctx.Err() != nil {
+			logger.Warning("Interrupted, skipping the remaining reviews")
+			break
+		}
+
+		since, err := reviews.Since(url)
+		if err != nil {
+			logger.Error("Failed to read stored reviews of %s: %v", url, err)
+			continue
+		}
+
+		logger.Info("[%d/%d] Reviews: %s", i+1, len(urls), url)
+		found, err := scraper.ScrapeReviews(ctx, url, since)
+		if errors.Is(err, airbnb.ErrRequestBudgetExhausted) {
+			logger.Warning("Request budget exhausted, skipping the remaining reviews")
+			break
+		}
+		if err != nil {
+			// Nothing is saved, so the next run fetches this listing's reviews again
+			logger.Error("Failed to scrape reviews of %s: %v", url, err)
+			continue
+		}
+		if len(found) == 0 {
+			continue
+		}
+		saved += reviews.SaveReviews(url, found)
+	}
+	return saved
+}
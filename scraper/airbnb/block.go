@@ -0,0 +1,148 @@
+package airbnb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/scraper/dom"
+)
+
+// PageClass is how a page load turned out
+type PageClass string
+
+const (
+	PageOK          PageClass = "ok"
+	PageForbidden   PageClass = "forbidden"    // HTTP 403
+	PageRateLimited PageClass = "rate_limited" // HTTP 429
+	PageChallenge   PageClass = "challenge"    // CAPTCHA or bot check page
+	PageEmpty       PageClass = "empty"        // loaded without the expected content
+	PageTimeout     PageClass = "timeout"
+	PageFailed      PageClass = "error" // any other failure
+)
+
+// PageClasses lists every class in the order the run summary prints them
+var PageClasses = []PageClass{
+	PageOK, PageForbidden, PageRateLimited, PageChallenge, PageEmpty, PageTimeout, PageFailed,
+}
+
+// Blocked reports whether the class means Airbnb is refusing the scraper
+func (c PageClass) Blocked() bool {
+	return c == PageForbidden || c == PageRateLimited || c == PageChallenge
+}
+
+// LoadError is returned by page loads that did not produce the expected page
+type LoadError struct {
+	URL   string
+	Class PageClass
+	Err   error
+}
+
+func (e *LoadError) Error() string {
+	return fmt.Sprintf("%v [%s]", e.Err, e.Class)
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// ClassOf returns the class of a failed page load, or PageFailed for other errors
+func ClassOf(err error) PageClass {
+	if err == nil {
+		return PageOK
+	}
+	var loadErr *LoadError
+	if errors.As(err, &loadErr) {
+		return loadErr.Class
+	}
+	return PageFailed
+}
+
+// classifyPage decides how a fetch turned out.
+// fetchErr is the fetcher's error; page and doc are nil when nothing loaded.
+func classifyPage(page *Page, doc *dom.Node, fetchErr error, profile *SelectorProfile) PageClass {
+	if page != nil {
+		switch page.StatusCode {
+		case http.StatusForbidden:
+			return PageForbidden
+		case http.StatusTooManyRequests:
+			return PageRateLimited
+		}
+	}
+	if doc != nil && isChallenge(doc, profile) {
+		return PageChallenge
+	}
+	if fetchErr != nil {
+		if isTimeout(fetchErr) {
+			return PageTimeout
+		}
+		return PageFailed
+	}
+	return PageOK
+}
+
+// isTimeout reports whether err comes from a deadline rather than a refusal
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr interface{ Timeout() bool }
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isChallenge reports whether the page is a CAPTCHA or bot check instead of content
+func isChallenge(doc *dom.Node, profile *SelectorProfile) bool {
+	for _, selector := range profile.Block.Challenge {
+		if doc.FindFirst(selector) != nil {
+			return true
+		}
+	}
+
+	if len(profile.challengeText) == 0 {
+		return false
+	}
+	texts := []string{}
+	if title := doc.FindFirst("title"); title != nil {
+		texts = append(texts, title.Text())
+	}
+	if body := doc.FindFirst("body"); body != nil {
+		texts = append(texts, body.Text())
+	}
+	for _, text := range texts {
+		for _, pattern := range profile.challengeText {
+			if pattern.MatchString(text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pageCounter counts page loads by class
+type pageCounter struct {
+	mu     sync.Mutex
+	counts map[PageClass]int
+}
+
+func (c *pageCounter) add(class PageClass) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts == nil {
+		c.counts = make(map[PageClass]int)
+	}
+	c.counts[class]++
+}
+
+func (c *pageCounter) snapshot() map[PageClass]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := make(map[PageClass]int, len(c.counts))
+	for class, n := range c.counts {
+		counts[class] = n
+	}
+	return counts
+}
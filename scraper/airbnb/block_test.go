@@ -0,0 +1,59 @@
+package airbnb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/config"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// stubFetcher returns the same page and error for every request
+type stubFetcher struct {
+	html string
+	err  error
+}
+
+func (f stubFetcher) Fetch(ctx context.Context, req PageRequest) (*Page, error) {
+	if f.html == "" {
+		return nil, f.err
+	}
+	return &Page{URL: req.URL, StatusCode: 200, HTML: f.html}, f.err
+}
+
+func (f stubFetcher) Close() error {
+	return nil
+}
+
+func TestLoadDocumentWaitTimeout(t *testing.T) {
+	timeout := fmt.Errorf("wait selector never appeared: %w", context.DeadlineExceeded)
+
+	tests := []struct {
+		name    string
+		fetcher stubFetcher
+		want    PageClass
+	}{
+		{"no results rendered", stubFetcher{html: `<html><body><h1>No exact matches</h1></body></html>`, err: timeout}, PageEmpty},
+		{"challenge rendered", stubFetcher{html: `<html><body><div id="px-captcha"></div></body></html>`, err: timeout}, PageChallenge},
+		{"results rendered late", stubFetcher{html: `<html><body><div data-testid="card-container"></div></body></html>`, err: timeout}, PageOK},
+		{"nothing rendered", stubFetcher{err: timeout}, PageTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewScraperWithFetcher(&config.ScraperConfig{MaxPages: 1}, utils.NewLogger(), tt.fetcher, testProfile(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, err = s.loadDocument(context.Background(), PageRequest{
+				URL:          "https://www.airbnb.com/s/Nowhere/homes",
+				WaitSelector: anyOf(s.profile.Search.Card),
+			})
+			if got := ClassOf(err); got != tt.want {
+				t.Errorf("class = %s (%v), want %s", got, err, tt.want)
+			}
+		})
+	}
+}
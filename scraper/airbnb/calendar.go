@@ -0,0 +1,138 @@
+package airbnb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/models"
+	"github.com/farhanasfar/airbnb-market-scraping-system/scraper/dom"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// calendarPath is the API the listing page's date picker loads its months from
+const calendarPath = "/api/v3/PdpAvailabilityCalendar"
+
+// CalendarURL returns the URL of a listing's availability calendar covering
+// months months from the month of start
+func CalendarURL(baseURL, roomID string, start time.Time, months int, apiKey, queryHash string) string {
+	variables, _ := json.Marshal(map[string]any{
+		"request": map[string]any{
+			"count":     months,
+			"listingId": roomID,
+			"month":     int(start.Month()),
+			"year":      start.Year(),
+		},
+	})
+	extensions, _ := json.Marshal(map[string]any{
+		"persistedQuery": map[string]any{"version": 1, "sha256Hash": queryHash},
+	})
+
+	values := url.Values{}
+	values.Set("operationName", "PdpAvailabilityCalendar")
+	values.Set("locale", "en")
+	values.Set("variables", string(variables))
+	values.Set("extensions", string(extensions))
+	if apiKey != "" {
+		values.Set("key", apiKey)
+	}
+	return strings.TrimSuffix(baseURL, "/") + calendarPath + "/" + queryHash + "?" + values.Encode()
+}
+
+// ScrapeCalendar loads a listing's availability calendar from today's month on and
+// returns the nights from today onwards, stamped with today as the snapshot date
+func (s *Scraper) ScrapeCalendar(ctx context.Context, listingURL string, today time.Time) ([]models.CalendarDay, error) {
+	id := RoomIDFromURL(listingURL)
+	if id == "" {
+		return nil, fmt.Errorf("no room id in %s", listingURL)
+	}
+
+	cfg := s.cfg.Calendar
+	months := cfg.Months
+	if months <= 0 {
+		months = 12 // Default
+	}
+	if cfg.QueryHash == "" {
+		return nil, fmt.Errorf("calendar query_hash is not configured")
+	}
+
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	page, doc, err := s.loadDocument(ctx, PageRequest{
+		URL: CalendarURL(s.cfg.BaseURL, id, today, months, s.cfg.APIKey, cfg.QueryHash),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load calendar: %w", err)
+	}
+
+	days, err := ExtractCalendar(page, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := make([]models.CalendarDay, 0, len(days))
+	for _, day := range days {
+		if day.Date.Before(today) {
+			continue // the first month starts before today
+		}
+		day.URL = utils.NormalizeURL(listingURL)
+		day.SnapshotDate = today
+		snapshot = append(snapshot, day)
+	}
+	return snapshot, nil
+}
+
+// ExtractCalendar reads the days of every month in a calendar API answer
+func ExtractCalendar(page *Page, doc *dom.Node) ([]models.CalendarDay, error) {
+	dec := json.NewDecoder(bytes.NewReader(jsonBody(page, doc)))
+	dec.UseNumber()
+	var data any
+	if err := dec.Decode(&data); err != nil {
+		return nil, fmt.Errorf("invalid calendar JSON: %w", err)
+	}
+
+	var days []models.CalendarDay
+	found := false
+	walkObjects(data, func(obj map[string]any) {
+		months, ok := obj["calendarMonths"].([]any)
+		if !ok || found {
+			return
+		}
+		found = true
+		for _, month := range months {
+			items, _ := lookup(month, "days").([]any)
+			for _, item := range items {
+				date, err := time.Parse(dateLayout, stringAt(item, "calendarDate"))
+				if err != nil {
+					continue
+				}
+				available, _ := lookup(item, "available").(bool)
+				checkIn, _ := lookup(item, "availableForCheckin").(bool)
+				checkOut, _ := lookup(item, "availableForCheckout").(bool)
+				days = append(days, models.CalendarDay{
+					Date:      date,
+					Available: available,
+					CheckIn:   checkIn,
+					CheckOut:  checkOut,
+					MinNights: int(numberAt(item, "minNights")),
+					MaxNights: int(numberAt(item, "maxNights")),
+				})
+			}
+		}
+	})
+
+	if !found {
+		message := stringAt(data, "error_message")
+		if errs, ok := lookup(data, "errors").([]any); ok && len(errs) > 0 {
+			message = stringAt(errs[0], "message")
+		}
+		if message != "" {
+			return nil, fmt.Errorf("calendar endpoint error: %s", message)
+		}
+		return nil, fmt.Errorf("no calendar in response")
+	}
+	return days, nil
+}
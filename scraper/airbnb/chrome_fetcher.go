@@ -0,0 +1,408 @@
+package airbnb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"github.com/farhanasfar/airbnb-market-scraping-system/config"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// defaultTabMaxUses is how many pages a tab loads before it is replaced
+const defaultTabMaxUses = 20
+
+// captureTimeout bounds capturing a page whose wait selector never showed up
+const captureTimeout = 5 * time.Second
+
+// ChromeFetcher loads pages in a real Chrome instance driven by chromedp.
+// One browser process is started per run; fetches borrow tabs from a bounded pool.
+type ChromeFetcher struct {
+	cfg    *config.ScraperConfig
+	logger *utils.Logger
+
+	mu            sync.Mutex
+	cancelAlloc   context.CancelFunc
+	browserCtx    context.Context
+	cancelBrowser context.CancelFunc
+
+	slots        chan struct{} // one slot per tab that may be open
+	idle         chan *tab
+	maxUses      int
+	fingerprints *FingerprintPool
+	proxies      *ProxyPool
+}
+
+// tab is a browser tab owned by the pool.
+// Each tab is one session and keeps its fingerprint until it is recycled.
+type tab struct {
+	ctx         context.Context
+	cancel      context.CancelFunc
+	uses        int
+	fingerprint config.Fingerprint
+	proxy       *Proxy
+	retired     atomic.Bool // set when the session was blocked; the tab is closed instead of reused
+}
+
+// NewChromeFetcher creates a chromedp-backed fetcher.
+// Chrome is started lazily on the first fetch. proxies may be nil for direct connections.
+func NewChromeFetcher(cfg *config.ScraperConfig, logger *utils.Logger, proxies *ProxyPool) *ChromeFetcher {
+	poolSize := cfg.TabPoolSize
+	if poolSize <= 0 {
+		poolSize = cfg.MaxWorkers
+	}
+	if poolSize <= 0 {
+		poolSize = 3 // Default
+	}
+
+	maxUses := cfg.TabMaxUses
+	if maxUses <= 0 {
+		maxUses = defaultTabMaxUses
+	}
+
+	return &ChromeFetcher{
+		cfg:          cfg,
+		logger:       logger,
+		slots:        make(chan struct{}, poolSize),
+		idle:         make(chan *tab, poolSize),
+		maxUses:      maxUses,
+		fingerprints: NewFingerprintPool(cfg),
+		proxies:      proxies,
+	}
+}
+
+// startBrowser launches Chrome with anti-detection settings.
+// User agent and viewport are set per tab from its fingerprint.
+// Callers must hold f.mu.
+func (f *ChromeFetcher) startBrowser() error {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("headless", f.cfg.Headless),
+		chromedp.WindowSize(1920, 1080),
+		chromedp.Flag("disable-blink-features", "AutomationControlled"),
+		chromedp.Flag("blink-settings", "imagesEnabled=false"),
+	)
+
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), opts...)
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx)
+
+	// Running with no actions starts the browser process
+	if err := chromedp.Run(browserCtx); err != nil {
+		cancelBrowser()
+		cancelAlloc()
+		return fmt.Errorf("failed to start browser: %w", err)
+	}
+
+	f.cancelAlloc = cancelAlloc
+	f.browserCtx, f.cancelBrowser = browserCtx, cancelBrowser
+	f.logger.Info("Started browser (tab pool size %d, %d uses per tab)", cap(f.slots), f.maxUses)
+	return nil
+}
+
+// stopBrowser shuts Chrome down. Callers must hold f.mu.
+func (f *ChromeFetcher) stopBrowser() {
+	if f.cancelBrowser != nil {
+		f.cancelBrowser()
+		f.cancelAlloc()
+	}
+	f.cancelAlloc = nil
+	f.browserCtx, f.cancelBrowser = nil, nil
+}
+
+// newTab opens a tab, restarting the browser if it has gone away.
+// With proxies configured each tab gets its own browser context pinned to one proxy.
+func (f *ChromeFetcher) newTab() (*tab, error) {
+	proxy, err := f.proxies.Acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.browserCtx == nil || f.browserCtx.Err() != nil {
+		f.stopBrowser()
+		if err := f.startBrowser(); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := f.openTab(proxy)
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+
+		// The browser may have crashed; start a fresh one and try once more
+		f.logger.Warning("Failed to open tab, restarting browser: %v", err)
+		f.stopBrowser()
+		if err := f.startBrowser(); err != nil {
+			return nil, err
+		}
+		ctx, cancel = f.openTab(proxy)
+		if err := chromedp.Run(ctx); err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to open tab: %w", err)
+		}
+	}
+
+	t := &tab{ctx: ctx, cancel: cancel, fingerprint: f.fingerprints.Next(), proxy: proxy}
+	if err := chromedp.Run(ctx, applyFingerprint(t.fingerprint)); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to apply fingerprint %s: %w", t.fingerprint.Name, err)
+	}
+	if proxy != nil && proxy.URL.User != nil {
+		if err := enableProxyAuth(ctx, proxy); err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to set up proxy auth for %s: %w", proxy, err)
+		}
+	}
+
+	if proxy != nil {
+		f.logger.Info("Opened tab with fingerprint %s via proxy %s", t.fingerprint.Name, proxy)
+	} else {
+		f.logger.Info("Opened tab with fingerprint %s", t.fingerprint.Name)
+	}
+
+	return t, nil
+}
+
+// openTab creates a tab context, in a separate browser context when it needs its own proxy.
+// Callers must hold f.mu.
+func (f *ChromeFetcher) openTab(proxy *Proxy) (context.Context, context.CancelFunc) {
+	if proxy == nil {
+		return chromedp.NewContext(f.browserCtx)
+	}
+	return chromedp.NewContext(f.browserCtx, chromedp.WithNewBrowserContext(
+		func(p *target.CreateBrowserContextParams) *target.CreateBrowserContextParams {
+			return p.WithProxyServer(proxy.Server())
+		}))
+}
+
+// enableProxyAuth answers the proxy's credential challenges for a tab.
+// Intercepting auth pauses every request, so paused requests are continued as-is.
+func enableProxyAuth(ctx context.Context, proxy *Proxy) error {
+	username := proxy.URL.User.Username()
+	password, _ := proxy.URL.User.Password()
+
+	chromedp.ListenTarget(ctx, func(ev any) {
+		switch e := ev.(type) {
+		case *fetch.EventRequestPaused:
+			go chromedp.Run(ctx, fetch.ContinueRequest(e.RequestID))
+		case *fetch.EventAuthRequired:
+			go chromedp.Run(ctx, fetch.ContinueWithAuth(e.RequestID, &fetch.AuthChallengeResponse{
+				Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+				Username: username,
+				Password: password,
+			}))
+		}
+	})
+
+	return chromedp.Run(ctx, fetch.Enable().WithHandleAuthRequests(true))
+}
+
+// applyFingerprint makes a tab present the fingerprint's browser identity
+func applyFingerprint(fp config.Fingerprint) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		ua := emulation.SetUserAgentOverride(fp.UserAgent).WithAcceptLanguage(fp.AcceptLanguage)
+		if fp.Platform != "" {
+			ua = ua.WithPlatform(fp.Platform)
+		}
+		if err := ua.Do(ctx); err != nil {
+			return err
+		}
+
+		err := emulation.SetDeviceMetricsOverride(int64(fp.ViewportWidth), int64(fp.ViewportHeight), 1, false).Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		if fp.Timezone != "" {
+			return emulation.SetTimezoneOverride(fp.Timezone).Do(ctx)
+		}
+		return nil
+	})
+}
+
+// acquire borrows a tab, waiting while all tabs are in use
+func (f *ChromeFetcher) acquire(ctx context.Context) (*tab, error) {
+	select {
+	case f.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if t := f.idleTab(); t != nil {
+		return t, nil
+	}
+
+	t, err := f.newTab()
+	if err != nil {
+		<-f.slots
+		return nil, err
+	}
+	return t, nil
+}
+
+// idleTab takes a reusable tab from the pool, closing retired tabs on the way
+func (f *ChromeFetcher) idleTab() *tab {
+	for {
+		select {
+		case t := <-f.idle:
+			if !t.retired.Load() {
+				return t
+			}
+			t.cancel()
+		default:
+			return nil
+		}
+	}
+}
+
+// release returns a tab to the pool, closing it if it failed or is worn out
+func (f *ChromeFetcher) release(t *tab, failed bool) {
+	t.uses++
+	if failed || t.uses >= f.maxUses || t.ctx.Err() != nil || t.retired.Load() {
+		t.cancel()
+	} else {
+		f.idle <- t
+	}
+	<-f.slots
+}
+
+// removeWebdriverProperty removes the webdriver property that sites check
+func removeWebdriverProperty() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		err := chromedp.Evaluate(`
+			Object.defineProperty(navigator, 'webdriver', {
+				get: () => undefined
+			})
+		`, nil).Do(ctx)
+		return err
+	})
+}
+
+// scrollForLazyContent scrolls halfway down and back up so lazy sections render
+func scrollForLazyContent() chromedp.Action {
+	return chromedp.Tasks{
+		chromedp.Evaluate(`window.scrollTo(0, document.body.scrollHeight / 2)`, nil),
+		chromedp.Sleep(2 * time.Second),
+		chromedp.Evaluate(`window.scrollTo(0, 0)`, nil),
+		chromedp.Sleep(2 * time.Second),
+	}
+}
+
+// Fetch navigates a pooled tab to the page and returns the rendered HTML
+func (f *ChromeFetcher) Fetch(ctx context.Context, req PageRequest) (*Page, error) {
+	t, err := f.acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", req.URL, err)
+	}
+
+	// Derive from the tab so ctx can abort the navigation without closing the tab
+	runCtx, cancel := context.WithCancel(t.ctx)
+	stop := context.AfterFunc(ctx, cancel)
+	defer func() {
+		stop()
+		cancel()
+	}()
+
+	page := &Page{
+		URL:         req.URL,
+		Fingerprint: t.fingerprint.Name,
+		retire:      func() { t.retired.Store(true) },
+	}
+
+	resp, err := chromedp.RunResponse(runCtx, removeWebdriverProperty(), chromedp.Navigate(req.URL))
+	if err == nil && resp != nil {
+		page.StatusCode = int(resp.Status)
+	}
+	if err == nil {
+		err = chromedp.Run(runCtx, renderActions(req, page))
+		if err != nil && req.WaitSelector != "" && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			// The wait selector never showed up. Capture what did render so the scraper
+			// can tell an empty results page or a challenge from a page that hung.
+			captureCtx, cancelCapture := context.WithTimeout(t.ctx, captureTimeout)
+			if chromedp.Run(captureCtx, captureActions(page)) != nil {
+				page.HTML = ""
+			}
+			cancelCapture()
+			err = fmt.Errorf("%s never appeared: %w", req.WaitSelector, ctx.Err())
+		}
+	}
+	if err == nil && page.StatusCode >= 400 {
+		// Return the error page too so the scraper can tell a block from other failures
+		err = fmt.Errorf("failed to load %s: HTTP %d", req.URL, page.StatusCode)
+		fault := statusFault(page.StatusCode)
+		f.release(t, fault != nil)
+		f.proxies.Report(t.proxy, fault)
+		return page, err
+	}
+
+	if err != nil && page.HTML != "" {
+		// The page loaded, so neither the tab nor the proxy is at fault
+		f.release(t, false)
+		return page, fmt.Errorf("failed to load %s: %w", req.URL, err)
+	}
+
+	f.release(t, err != nil)
+	if err != nil && ctx.Err() != nil {
+		// Cancelled by the caller, not the proxy's fault
+		return nil, fmt.Errorf("failed to load %s: %w", req.URL, ctx.Err())
+	}
+	f.proxies.Report(t.proxy, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", req.URL, err)
+	}
+
+	return page, nil
+}
+
+// renderActions waits for the loaded page to render and captures its HTML.
+// Error pages are captured as they are, since they never show the wait selector.
+func renderActions(req PageRequest, page *Page) chromedp.Tasks {
+	actions := chromedp.Tasks{}
+	if page.StatusCode < 400 {
+		if req.WaitSelector != "" {
+			actions = append(actions, chromedp.WaitVisible(req.WaitSelector, chromedp.ByQuery))
+		}
+		if req.Settle > 0 {
+			actions = append(actions, chromedp.Sleep(req.Settle))
+		}
+		if req.Scroll {
+			actions = append(actions, scrollForLazyContent())
+		}
+	}
+
+	return append(actions, captureActions(page)...)
+}
+
+// captureActions records the page's final URL and rendered HTML
+func captureActions(page *Page) chromedp.Tasks {
+	return chromedp.Tasks{
+		chromedp.Location(&page.URL),
+		chromedp.OuterHTML("html", &page.HTML, chromedp.ByQuery),
+	}
+}
+
+// Close closes all pooled tabs and shuts the browser down
+func (f *ChromeFetcher) Close() error {
+drain:
+	for {
+		select {
+		case t := <-f.idle:
+			t.cancel()
+		default:
+			break drain
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stopBrowser()
+	return nil
+}
@@ -0,0 +1,115 @@
+package airbnb
+
+import (
+	"fmt"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/config"
+	"github.com/farhanasfar/airbnb-market-scraping-system/models"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// Crawl modes selectable through scraper.crawl_mode in config
+const (
+	CrawlPages = "pages" // max_pages pages, keeping the first properties_per_page cards of each
+	CrawlAll   = "all"   // every card until the results run out
+	CrawlCount = "count" // until target_listings unique listings
+	CrawlStale = "stale" // until stale_pages pages in a row bring no new room ids
+)
+
+// crawlLimits decides how much of a location's results to scrape
+type crawlLimits struct {
+	mode       string
+	maxPages   int
+	perPage    int
+	target     int
+	stalePages int
+}
+
+func newCrawlLimits(cfg *config.ScraperConfig) (crawlLimits, error) {
+	limits := crawlLimits{
+		mode:       cfg.CrawlMode,
+		maxPages:   cfg.MaxPages,
+		perPage:    cfg.PropertiesPerPage,
+		target:     cfg.TargetListings,
+		stalePages: cfg.StalePages,
+	}
+	if limits.mode == "" {
+		limits.mode = CrawlPages
+	}
+
+	switch limits.mode {
+	case CrawlPages, CrawlAll:
+	case CrawlCount:
+		if limits.target <= 0 {
+			return limits, fmt.Errorf("crawl_mode %q needs target_listings", CrawlCount)
+		}
+	case CrawlStale:
+		if limits.stalePages <= 0 {
+			limits.stalePages = 2
+		}
+	default:
+		return limits, fmt.Errorf("unknown crawl_mode %q (expected %q, %q, %q or %q)",
+			limits.mode, CrawlPages, CrawlAll, CrawlCount, CrawlStale)
+	}
+	return limits, nil
+}
+
+func (l crawlLimits) String() string {
+	switch l.mode {
+	case CrawlAll:
+		return "all results"
+	case CrawlCount:
+		return fmt.Sprintf("until %d unique listings", l.target)
+	case CrawlStale:
+		return fmt.Sprintf("until %d pages in a row with no new listings", l.stalePages)
+	}
+	return fmt.Sprintf("%d properties per page × %d pages = %d total", l.perPage, l.maxPages, l.perPage*l.maxPages)
+}
+
+// keep trims a page's cards to the ones this mode scrapes, before deduplication
+func (l crawlLimits) keep(listings []models.RawListing) []models.RawListing {
+	if l.mode == CrawlPages && len(listings) > l.perPage {
+		return listings[:l.perPage]
+	}
+	return listings
+}
+
+// room trims new listings so the count mode stops exactly at its target
+func (l crawlLimits) room(fresh []models.RawListing, found int) []models.RawListing {
+	if l.mode == CrawlCount && found+len(fresh) > l.target {
+		return fresh[:max(l.target-found, 0)]
+	}
+	return fresh
+}
+
+// done reports whether the crawl stops after page, with found unique listings so
+// far and the last stale pages bringing nothing new
+func (l crawlLimits) done(page, found, stale int) bool {
+	switch l.mode {
+	case CrawlPages:
+		return page >= l.maxPages
+	case CrawlCount:
+		return found >= l.target
+	case CrawlStale:
+		return stale >= l.stalePages
+	}
+	return false
+}
+
+// dedupeListings drops listings whose room id (or URL, when the id is unknown)
+// is already in seen, and adds the rest to seen
+func dedupeListings(listings []models.RawListing, seen map[string]bool) []models.RawListing {
+	fresh := make([]models.RawListing, 0, len(listings))
+	for _, listing := range listings {
+		key := listing.RoomID
+		if key == "" {
+			key = utils.NormalizeURL(listing.URL)
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		fresh = append(fresh, listing)
+	}
+	return fresh
+}
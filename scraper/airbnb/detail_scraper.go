@@ -2,125 +2,275 @@ package airbnb
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/chromedp/chromedp"
+	"github.com/farhanasfar/airbnb-market-scraping-system/models"
+	"github.com/farhanasfar/airbnb-market-scraping-system/scraper/dom"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
 )
 
 // DetailResult holds the result of scraping a detail page
 type DetailResult struct {
-	URL       string
-	Bedrooms  int
-	Bathrooms int
-	Guests    int
-	Error     error
+	URL         string
+	Bedrooms    int
+	Bathrooms   int
+	Guests      int
+	Rating      float64
+	ReviewCount int
+	Latitude    float64
+	Longitude   float64
+
+	// Where the listing is, from the detail page's location section
+	Neighborhood string
+	City         string
+	Region       string
+	Country      string
+
+	// Amenities, host and house rules
+	models.ListingDetails
+
+	// Price breakdown for the configured quote stay; nil when quotes are off or the page showed none
+	Quote *models.PriceQuote
+
+	Error error
 }
 
-// ScrapeDetailPage extracts bedroom, bathroom, and guest info from a listing detail page
+// ScrapeDetailPage extracts room counts, amenities, host and house rules from a listing detail page
 func (s *Scraper) ScrapeDetailPage(ctx context.Context, url string) (*DetailResult, error) {
 	result := &DetailResult{URL: url}
 
-	// Create a new browser context for this detail page
-	browserCtx, cancel := s.createStealthContext(ctx)
-	defer cancel()
-
-	// Add timeout for detail page
-	browserCtx, cancel = context.WithTimeout(browserCtx, 30*time.Second)
-	defer cancel()
-
 	s.logger.Info("Scraping detail page: %s", url)
 
-	// Add rate limiting delay before visiting
-	// if err := chromedp.Sleep(2 * time.Second).Do(browserCtx); err != nil {
-	// 	return result, fmt.Errorf("delay failed: %w", err)
-	// }
-
-	var detailsJSON string
-
-	err := chromedp.Run(browserCtx,
-		removeWebdriverProperty(),
-		chromedp.Navigate(url),
-
-		// Wait for the page to load - looking for common Airbnb detail page elements
-		chromedp.WaitVisible(`[data-section-id="OVERVIEW_DEFAULT"]`, chromedp.ByQuery),
-
-		// Extract details using JavaScript
-		chromedp.Evaluate(`
-			JSON.stringify({
-				bedrooms: (() => {
-					// Try multiple selectors for bedrooms
-					const bedroomText = Array.from(document.querySelectorAll('li, span, div'))
-						.map(el => el.innerText)
-						.find(text => /(\d+)\s*(bedroom|bed)/i.test(text));
-					if (bedroomText) {
-						const match = bedroomText.match(/(\d+)\s*(bedroom|bed)/i);
-						return match ? parseInt(match[1]) : 0;
-					}
-					return 0;
-				})(),
-				bathrooms: (() => {
-					// Try multiple selectors for bathrooms
-					const bathroomText = Array.from(document.querySelectorAll('li, span, div'))
-						.map(el => el.innerText)
-						.find(text => /(\d+\.?\d*)\s*bath/i.test(text));
-					if (bathroomText) {
-						const match = bathroomText.match(/(\d+\.?\d*)\s*bath/i);
-						return match ? parseFloat(match[1]) : 0;
-					}
-					return 0;
-				})(),
-				guests: (() => {
-					// Try multiple selectors for guests
-					const guestText = Array.from(document.querySelectorAll('li, span, div'))
-						.map(el => el.innerText)
-						.find(text => /(\d+)\s*guest/i.test(text));
-					if (guestText) {
-						const match = guestText.match(/(\d+)\s*guest/i);
-						return match ? parseInt(match[1]) : 0;
-					}
-					return 0;
-				})()
-			})
-		`, &detailsJSON),
-	)
+	// With a quote stay configured the page is loaded priced for it
+	pageURL := url
+	if s.quote != nil {
+		pageURL = s.quote.url(url)
+	}
 
+	// Wait for the page to load - looking for common Airbnb detail page elements
+	page, doc, err := s.loadDocument(ctx, PageRequest{
+		URL:          pageURL,
+		WaitSelector: anyOf(s.profile.Detail.Ready),
+	})
 	if err != nil {
 		result.Error = fmt.Errorf("failed to scrape detail page: %w", err)
 		return result, result.Error
 	}
 
-	// Parse the JSON response
-	var details struct {
-		Bedrooms  int     `json:"bedrooms"`
-		Bathrooms float64 `json:"bathrooms"`
-		Guests    int     `json:"guests"`
+	// Prefer the embedded JSON state; the page text is only a fallback
+	source := "state"
+	if !ExtractDetailsFromState(doc, result, s.profile) {
+		s.logger.Warning("No embedded state on %s, falling back to page text", url)
+		source = "selectors"
+		ExtractDetailsFromDOM(doc, result, s.profile)
 	}
 
-	if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil {
-		result.Error = fmt.Errorf("failed to parse details JSON: %w", err)
-		return result, result.Error
+	if s.quote != nil {
+		if quote := ExtractQuote(doc, page.URL, s.profile); quote != nil {
+			quote.URL = url
+			quote.CheckIn = s.quote.CheckIn
+			quote.CheckOut = s.quote.CheckOut
+			quote.Guests = s.quote.adults
+			if quote.Nights == 0 {
+				quote.Nights = s.quote.Nights()
+			}
+			result.Quote = quote
+		} else {
+			s.logger.Warning("No price breakdown on %s for %s", url, s.quote)
+		}
 	}
 
-	result.Bedrooms = details.Bedrooms
-	result.Bathrooms = int(details.Bathrooms) // Convert to int for storage
-	result.Guests = details.Guests
-
-	s.logger.Success("Detail page scraped: %d beds, %d baths, %d guests",
-		result.Bedrooms, result.Bathrooms, result.Guests)
+	s.logger.Success("Detail page scraped: %d bedrooms, %d baths, %d guests, %d amenities [profile %s, %s, fingerprint %s]",
+		result.Bedrooms, result.Bathrooms, result.Guests, len(result.Amenities), s.profile.Version, source, page.Fingerprint)
 
 	return result, nil
 }
 
+// ExtractDetailsFromDOM fills a detail result from a detail page's text.
+// Each value comes from the first text element whose text matches the field's patterns.
+func ExtractDetailsFromDOM(doc *dom.Node, result *DetailResult, profile *SelectorProfile) {
+	texts := []string{}
+	for _, el := range firstMatchAll(doc, profile.Detail.Text) {
+		texts = append(texts, el.Text())
+	}
+
+	if result.City == "" {
+		if address := firstValue(doc, profile.Detail.Address); address != "" {
+			applyAddress(address, result)
+		}
+	}
+
+	if len(result.Amenities) == 0 {
+		for _, el := range firstMatchAll(doc, profile.Detail.Amenities) {
+			// The full list also shows what the listing lacks, struck through
+			if text := el.Text(); !strings.HasPrefix(strings.TrimSpace(text), "Unavailable") {
+				result.Amenities = appendAmenity(result.Amenities, text)
+			}
+		}
+	}
+
+	applyDetailPatterns(texts, result, profile, PageLocale(doc, result.URL))
+}
+
+// applyDetailPatterns sets the room counts from the first texts the profile's patterns
+// match, and fills the other fields the embedded state did not already provide.
+// Counts are read the way the page's locale writes numbers.
+func applyDetailPatterns(texts []string, result *DetailResult, profile *SelectorProfile, locale utils.Locale) {
+	// Patterns match ASCII digits, so Arabic-Indic or Devanagari digits are rewritten first
+	normalized := make([]string, len(texts))
+	for i, text := range texts {
+		normalized[i] = utils.NormalizeDigits(text)
+	}
+	texts = normalized
+
+	match := func(field string) string {
+		if m := firstPatternMatch(texts, profile.patterns[field]); m != nil {
+			return strings.TrimSpace(m[1])
+		}
+		return ""
+	}
+
+	count := func(field string) (int, bool) {
+		value := match(field)
+		if value == "" {
+			return 0, false
+		}
+		n := locale.ParseCount(value)
+		return int(n.Value), n.OK() // "1.5 baths" -> 1 for storage
+	}
+
+	if n, ok := count("bedrooms"); ok {
+		result.Bedrooms = n
+	}
+	if n, ok := count("bathrooms"); ok {
+		result.Bathrooms = n
+	}
+	if n, ok := count("guests"); ok {
+		result.Guests = n
+	}
+
+	if result.Beds == 0 {
+		result.Beds, _ = count("beds")
+	}
+	if result.PropertyType == "" {
+		result.PropertyType = match("property_type")
+	}
+	if result.RoomType == "" {
+		result.RoomType = roomTypeOf(result.PropertyType)
+	}
+	if result.HostName == "" {
+		result.HostName = match("host_name")
+	}
+	if !result.Superhost {
+		result.Superhost = match("superhost") != ""
+	}
+	if result.HostJoinYear == 0 {
+		result.HostJoinYear, _ = strconv.Atoi(match("host_joined"))
+	}
+	if result.HostResponseRate == 0 {
+		result.HostResponseRate, _ = strconv.Atoi(match("response_rate"))
+	}
+	if result.CheckInTime == "" {
+		result.CheckInTime = strings.ToUpper(match("check_in"))
+	}
+	if result.CheckOutTime == "" {
+		result.CheckOutTime = strings.ToUpper(match("check_out"))
+	}
+	if result.MinNights == 0 {
+		result.MinNights, _ = strconv.Atoi(match("min_nights"))
+	}
+	if result.CancellationPolicy == "" {
+		result.CancellationPolicy = match("cancellation_policy")
+	}
+	if !result.InstantBook {
+		result.InstantBook = match("instant_book") != ""
+	}
+}
+
+// roomTypeOf derives Airbnb's room type from a property type such as
+// "Private room in home", or "" when the property type does not say
+func roomTypeOf(propertyType string) string {
+	lower := strings.ToLower(propertyType)
+	switch {
+	case lower == "":
+		return ""
+	case strings.HasPrefix(lower, "entire"):
+		return string(RoomEntireHome)
+	case strings.HasPrefix(lower, "private room"), strings.HasPrefix(lower, "room in"):
+		return string(RoomPrivateRoom)
+	case strings.HasPrefix(lower, "shared"):
+		return string(RoomSharedRoom)
+	case strings.Contains(lower, "hotel"):
+		return string(RoomHotelRoom)
+	}
+	return ""
+}
+
+// appendAmenity adds an amenity name once, ignoring blanks and repeats
+func appendAmenity(amenities []string, name string) []string {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return amenities
+	}
+	for _, existing := range amenities {
+		if strings.EqualFold(existing, name) {
+			return amenities
+		}
+	}
+	return append(amenities, name)
+}
+
+// findMatch returns the submatches of the first text the pattern matches
+func findMatch(texts []string, pattern *regexp.Regexp) []string {
+	for _, text := range texts {
+		if match := pattern.FindStringSubmatch(text); match != nil {
+			return match
+		}
+	}
+	return nil
+}
+
 // ScrapeDetailsWithWorkers scrapes multiple detail pages concurrently using worker pool
 func (s *Scraper) ScrapeDetailsWithWorkers(ctx context.Context, urls []string) map[string]*DetailResult {
 	results := make(map[string]*DetailResult)
-	resultsMux := &sync.Mutex{}
 
 	// Create a channel for URLs to process
 	urlChan := make(chan string, len(urls))
+	for _, url := range urls {
+		urlChan <- url
+	}
+	close(urlChan)
+
+	for result := range s.ScrapeDetailsStream(ctx, urlChan) {
+		results[result.URL] = result
+	}
+
+	if ctx.Err() != nil {
+		done := 0
+		for _, result := range results {
+			if result.Error == nil {
+				done++
+			}
+		}
+		s.logger.Warning("Detail scraping cancelled, %d of %d pages scraped", done, len(urls))
+		return results
+	}
+
+	s.logger.Success("All detail pages scraped")
+	return results
+}
+
+// ScrapeDetailsStream scrapes the detail pages sent on urls with a pool of workers
+// and delivers each result as soon as it is ready.
+// The returned channel is closed once urls is closed and drained, or ctx is cancelled.
+func (s *Scraper) ScrapeDetailsStream(ctx context.Context, urls <-chan string) <-chan *DetailResult {
+	results := make(chan *DetailResult)
 
 	// Create a wait group for workers
 	var wg sync.WaitGroup
@@ -138,32 +288,28 @@ func (s *Scraper) ScrapeDetailsWithWorkers(ctx context.Context, urls []string) m
 		go func(workerID int) {
 			defer wg.Done()
 
-			for url := range urlChan {
+			for url := range urls {
+				// Leave the remaining pages once the run is cancelled
+				if ctx.Err() != nil {
+					break
+				}
+
 				s.logger.Info("[Worker %d] Processing: %s", workerID, url)
 
 				// Scrape with retry logic
-				result := s.scrapeDetailWithRetry(ctx, url)
-
-				// Store result
-				resultsMux.Lock()
-				results[url] = result
-				resultsMux.Unlock()
+				results <- s.scrapeDetailWithRetry(ctx, url)
 			}
 
 			s.logger.Info("[Worker %d] Finished", workerID)
 		}(i + 1)
 	}
 
-	// Send URLs to workers
-	for _, url := range urls {
-		urlChan <- url
-	}
-	close(urlChan)
-
-	// Wait for all workers to finish
-	wg.Wait()
+	// Close results once every worker is done
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
 
-	s.logger.Success("All detail pages scraped")
 	return results
 }
 
@@ -184,13 +330,30 @@ func (s *Scraper) scrapeDetailWithRetry(ctx context.Context, url string) *Detail
 			return result // Success
 		}
 
+		// No point retrying once the run is out of requests or cancelled
+		if errors.Is(lastErr, ErrRequestBudgetExhausted) || ctx.Err() != nil {
+			break
+		}
+
 		// Log retry attempt
 		if attempt < maxRetries {
 			s.logger.Warning("Attempt %d/%d failed for %s: %v. Retrying...",
 				attempt, maxRetries, url, lastErr)
 
-			// Wait before retry
-			time.Sleep(time.Duration(s.cfg.RetryDelayMs) * time.Millisecond)
+			// Blocks already paused the scheduler; other failures wait the retry delay
+			if !ClassOf(lastErr).Blocked() {
+				if err := sleepContext(ctx, time.Duration(s.cfg.RetryDelayMs)*time.Millisecond); err != nil {
+					lastErr = err
+					break
+				}
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		return &DetailResult{
+			URL:   url,
+			Error: fmt.Errorf("cancelled: %w", lastErr),
 		}
 	}
 
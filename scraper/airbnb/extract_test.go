@@ -0,0 +1,216 @@
+package airbnb
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/models"
+	"github.com/farhanasfar/airbnb-market-scraping-system/scraper/dom"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// replayDir holds saved pages laid out the way RecordingFetcher stores them
+const replayDir = "testdata/replay"
+
+// recordedOn is the day the dated pages in replayDir were recorded
+var recordedOn = time.Date(2026, 10, 7, 0, 0, 0, 0, time.UTC)
+
+const searchURL = "https://www.airbnb.com/s/Lisbon/homes?adults=2"
+
+// replayDocument loads a saved page through the replay fetcher and parses it
+func replayDocument(t *testing.T, pageURL string) *dom.Node {
+	t.Helper()
+	fetcher := NewReplayFetcher(replayDir, utils.NewLogger())
+	fetcher.today = recordedOn
+	page, err := fetcher.Fetch(context.Background(), PageRequest{URL: pageURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dom.Parse(page.HTML)
+}
+
+func testProfile(t *testing.T) *SelectorProfile {
+	t.Helper()
+	profile, err := LoadSelectorProfile("../../config/selectors.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return profile
+}
+
+func TestExtractSearchPages(t *testing.T) {
+	profile := testProfile(t)
+
+	tests := []struct {
+		name     string
+		url      string
+		listings []models.RawListing
+		next     string
+		pages    []string
+	}{
+		{
+			name: "first page with state cursors",
+			url:  searchURL,
+			listings: []models.RawListing{
+				{
+					RoomID:   "101",
+					Title:    "Sunny loft & terrace",
+					Price:    "$120 night",
+					Location: "Apartment in Alfama",
+					Rating:   "4.92 out of 5 average rating, 87 reviews",
+					URL:      "https://www.airbnb.com/rooms/101?adults=2&source_impression_id=p3",
+				},
+				{
+					// Unclosed <p> and <span> end with the card
+					RoomID:   "102",
+					Title:    "Garden house near the river",
+					Price:    "$95",
+					Location: "Home in Belém",
+					URL:      "https://www.airbnb.com/rooms/102",
+				},
+			},
+			// The state cursor wins over the link's items_offset
+			next: searchURL + "&cursor=Mg",
+			pages: []string{
+				searchURL + "&cursor=MQ",
+				searchURL + "&cursor=Mg",
+				searchURL + "&cursor=Mw",
+			},
+		},
+		{
+			name: "middle page with only a next link",
+			url:  searchURL + "&cursor=Mg",
+			listings: []models.RawListing{
+				{
+					RoomID:   "201",
+					Title:    "Riverside studio",
+					Price:    "$88",
+					Location: "Condo in Baixa",
+					Rating:   "New listing with no rating",
+					URL:      "https://www.airbnb.com/rooms/201",
+				},
+			},
+			// Only the cursor is taken from the link, not its host or search
+			next: searchURL + "&cursor=Mw",
+		},
+		{
+			name: "last page with a disabled next link",
+			url:  searchURL + "&cursor=Mw",
+			listings: []models.RawListing{
+				{
+					RoomID: "301",
+					Title:  "Last page flat",
+					Price:  "$70",
+					URL:    "https://www.airbnb.com/rooms/301",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := replayDocument(t, tt.url)
+
+			if got := ExtractListingsFromDOM(doc, tt.url, profile); !reflect.DeepEqual(got, tt.listings) {
+				t.Errorf("listings:\n got %+v\nwant %+v", got, tt.listings)
+			}
+			if got := ExtractNextPageURL(doc, tt.url, profile); got != tt.next {
+				t.Errorf("next page = %q, want %q", got, tt.next)
+			}
+			if got := ExtractPageURLs(doc, tt.url, profile); !reflect.DeepEqual(got, tt.pages) {
+				t.Errorf("page URLs = %q, want %q", got, tt.pages)
+			}
+		})
+	}
+}
+
+func TestExtractListingsFromStateBreakdown(t *testing.T) {
+	pageURL := "https://www.airbnb.com/s/Porto/homes?adults=2&checkin=2026-11-06&checkout=2026-11-09"
+	listings := ExtractListingsFromState(replayDocument(t, pageURL), pageURL, testProfile(t))
+	if len(listings) != 2 {
+		t.Fatalf("got %d listings, want 2", len(listings))
+	}
+
+	priced, plain := listings[0], listings[1]
+	if priced.Price != "$540 total" || priced.ReviewCount != 1204 {
+		t.Errorf("price %q, review count %d; want \"$540 total\", 1204", priced.Price, priced.ReviewCount)
+	}
+	want := &models.PriceQuote{
+		Nights:          3,
+		NightlyRate:     160,
+		OriginalPrice:   480,
+		DiscountedPrice: 450,
+		CleaningFee:     40,
+		ServiceFee:      50,
+		Total:           540,
+		Currency:        "USD",
+	}
+	if !reflect.DeepEqual(priced.Quote, want) {
+		t.Errorf("breakdown:\n got %+v\nwant %+v", priced.Quote, want)
+	}
+	if plain.Quote != nil {
+		t.Errorf("listing without explanationData got breakdown %+v", plain.Quote)
+	}
+}
+
+func TestExtractDetailPages(t *testing.T) {
+	profile := testProfile(t)
+
+	tests := []struct {
+		name string
+		url  string
+		want DetailResult
+	}{
+		{
+			name: "english overview, host, amenities and policies",
+			url:  "https://www.airbnb.com/rooms/101",
+			want: DetailResult{
+				Bedrooms:     2,
+				Bathrooms:    1,
+				Guests:       4,
+				Neighborhood: "Alfama",
+				City:         "Lisbon",
+				Region:       "Lisbon",
+				Country:      "Portugal",
+				ListingDetails: models.ListingDetails{
+					PropertyType:       "Entire rental unit",
+					RoomType:           string(RoomEntireHome),
+					Beds:               3,
+					Amenities:          []string{"Wifi", "Kitchen"},
+					HostName:           "Marta",
+					Superhost:          true,
+					HostJoinYear:       2017,
+					HostResponseRate:   98,
+					CheckInTime:        "3:00 PM",
+					CheckOutTime:       "11:00 AM",
+					CancellationPolicy: "Free cancellation before Nov 3",
+				},
+			},
+		},
+		{
+			name: "arabic-indic digits",
+			url:  "https://www.airbnb.com/rooms/102",
+			want: DetailResult{
+				Bedrooms:  3,
+				Bathrooms: 2,
+				Guests:    6,
+				City:      "Lisbon",
+				Country:   "Portugal",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := replayDocument(t, tt.url)
+
+			var got DetailResult
+			ExtractDetailsFromDOM(doc, &got, profile)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("details:\n got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
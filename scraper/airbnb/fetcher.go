@@ -0,0 +1,89 @@
+package airbnb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/config"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// Fetcher backends selectable through scraper.fetcher in config
+const (
+	FetcherChrome = "chromedp"
+	FetcherHTTP   = "http"
+)
+
+// PageRequest describes a page the scraper wants loaded
+type PageRequest struct {
+	URL string
+
+	// WaitSelector must be present before the page counts as loaded
+	WaitSelector string
+
+	// Settle is extra time browser backends give the page to render after loading
+	Settle time.Duration
+
+	// Scroll asks browser backends to scroll the page to trigger lazy-loaded content
+	Scroll bool
+}
+
+// Page is a loaded document handed to the extractors
+type Page struct {
+	URL        string
+	StatusCode int
+	HTML       string
+
+	// Fingerprint names the browser identity that fetched the page
+	Fingerprint string
+
+	// retire ends the session that fetched the page; nil for backends without sessions
+	retire func()
+}
+
+// retireSession keeps the fetcher from reusing the session that loaded the page,
+// so the next request starts with a fresh fingerprint and proxy
+func (p *Page) retireSession() {
+	if p.retire != nil {
+		p.retire()
+	}
+}
+
+// Fetcher loads pages for the scraper.
+// Extraction never talks to a browser directly, so any backend that can
+// return rendered HTML can drive the scraper.
+type Fetcher interface {
+	Fetch(ctx context.Context, req PageRequest) (*Page, error)
+	Close() error
+}
+
+// NewFetcher creates the fetcher backend selected in config.
+// A replay directory replaces the backend; a record directory wraps it.
+// proxies may be nil for direct connections.
+func NewFetcher(cfg *config.ScraperConfig, logger *utils.Logger, proxies *ProxyPool) (Fetcher, error) {
+	if cfg.ReplayDir != "" {
+		if cfg.RecordDir != "" {
+			return nil, fmt.Errorf("replay and record directories cannot be used together")
+		}
+		logger.Info("Replaying pages from %s", cfg.ReplayDir)
+		return NewReplayFetcher(cfg.ReplayDir, logger), nil
+	}
+
+	var fetcher Fetcher
+	switch cfg.Fetcher {
+	case "", FetcherChrome:
+		fetcher = NewChromeFetcher(cfg, logger, proxies)
+	case FetcherHTTP:
+		fetcher = NewHTTPFetcher(cfg, logger, proxies)
+	default:
+		return nil, fmt.Errorf("unknown fetcher %q (expected %q or %q)", cfg.Fetcher, FetcherChrome, FetcherHTTP)
+	}
+
+	if cfg.RecordDir != "" {
+		logger.Info("Recording pages to %s", cfg.RecordDir)
+		fetcher = NewRecordingFetcher(fetcher, cfg.RecordDir, logger)
+	}
+
+	return fetcher, nil
+}
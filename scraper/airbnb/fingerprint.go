@@ -0,0 +1,71 @@
+package airbnb
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/config"
+)
+
+// defaultFingerprint is used when config lists no fingerprints
+var defaultFingerprint = config.Fingerprint{
+	Name:           "mac-chrome",
+	UserAgent:      "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+	ViewportWidth:  1440,
+	ViewportHeight: 900,
+	AcceptLanguage: "en-US,en;q=0.9",
+	Platform:       "MacIntel",
+}
+
+// FingerprintPool hands out browser fingerprints round-robin, one per session.
+// The starting point is random so consecutive runs do not all open with the same identity.
+type FingerprintPool struct {
+	mu           sync.Mutex
+	fingerprints []config.Fingerprint
+	next         int
+}
+
+// NewFingerprintPool creates a pool from the fingerprints in config
+func NewFingerprintPool(cfg *config.ScraperConfig) *FingerprintPool {
+	fingerprints := make([]config.Fingerprint, 0, len(cfg.Fingerprints))
+	for _, fp := range cfg.Fingerprints {
+		fingerprints = append(fingerprints, withDefaults(fp))
+	}
+	if len(fingerprints) == 0 {
+		fingerprints = append(fingerprints, defaultFingerprint)
+	}
+
+	return &FingerprintPool{
+		fingerprints: fingerprints,
+		next:         rand.New(rand.NewSource(time.Now().UnixNano())).Intn(len(fingerprints)),
+	}
+}
+
+// Next returns the fingerprint for a new session
+func (p *FingerprintPool) Next() config.Fingerprint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fp := p.fingerprints[p.next]
+	p.next = (p.next + 1) % len(p.fingerprints)
+	return fp
+}
+
+// withDefaults fills fields a configured fingerprint leaves empty
+func withDefaults(fp config.Fingerprint) config.Fingerprint {
+	if fp.UserAgent == "" {
+		fp.UserAgent = defaultFingerprint.UserAgent
+	}
+	if fp.ViewportWidth <= 0 || fp.ViewportHeight <= 0 {
+		fp.ViewportWidth = defaultFingerprint.ViewportWidth
+		fp.ViewportHeight = defaultFingerprint.ViewportHeight
+	}
+	if fp.AcceptLanguage == "" {
+		fp.AcceptLanguage = defaultFingerprint.AcceptLanguage
+	}
+	if fp.Name == "" {
+		fp.Name = fp.UserAgent
+	}
+	return fp
+}
@@ -0,0 +1,56 @@
+package airbnb
+
+import (
+	"strings"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// applyAddress fills the place fields of a detail result from an address line such
+// as "Lisbon, Lisbon, Portugal". Airbnb lists the parts smallest first, ending with
+// the country; fields the result already has are kept.
+func applyAddress(address string, result *DetailResult) {
+	var parts []string
+	for _, part := range strings.Split(address, ",") {
+		if part = utils.CleanText(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	var neighborhood, city, region, country string
+	switch len(parts) {
+	case 0:
+		return
+	case 1:
+		city = parts[0]
+	case 2:
+		city, country = parts[0], parts[1]
+	case 3:
+		city, region, country = parts[0], parts[1], parts[2]
+	default:
+		n := len(parts)
+		neighborhood = strings.Join(parts[:n-3], ", ")
+		city, region, country = parts[n-3], parts[n-2], parts[n-1]
+	}
+
+	setIfEmpty(&result.Neighborhood, neighborhood)
+	setIfEmpty(&result.City, city)
+	setIfEmpty(&result.Region, region)
+	setIfEmpty(&result.Country, country)
+}
+
+func setIfEmpty(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+// PlaceFromTitle returns the place a card title names, e.g. "Richmond" for
+// "Loft in Richmond", or "" when the title does not name one
+func PlaceFromTitle(title string) string {
+	i := strings.LastIndex(title, " in ")
+	if i < 0 {
+		return ""
+	}
+	return utils.CleanText(title[i+len(" in "):])
+}
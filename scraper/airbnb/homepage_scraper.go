@@ -2,11 +2,11 @@ package airbnb
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
-	"github.com/chromedp/chromedp"
+	"github.com/farhanasfar/airbnb-market-scraping-system/scraper/dom"
 )
 
 type LocationCard struct {
@@ -16,74 +16,59 @@ type LocationCard struct {
 
 // ScrapeHomepageLocations extracts all visible location cards from Airbnb homepage
 func (s *Scraper) ScrapeHomepageLocations(ctx context.Context) ([]LocationCard, error) {
-	browserCtx, cancel := s.createStealthContext(ctx)
-	defer cancel()
-
 	s.logger.Info("Visiting Airbnb homepage to extract locations...")
 
-	var locationsJSON string
-
-	err := chromedp.Run(browserCtx,
-		removeWebdriverProperty(),
-		chromedp.Navigate(s.cfg.BaseURL),
-
-		// Wait for page to load
-		chromedp.Sleep(5*time.Second),
-
-		// Scroll down to load more location cards
-		chromedp.Evaluate(`window.scrollTo(0, document.body.scrollHeight / 2)`, nil),
-		chromedp.Sleep(2*time.Second),
-		chromedp.Evaluate(`window.scrollTo(0, 0)`, nil),
-		chromedp.Sleep(2*time.Second),
-
-		// Extract all location cards
-		chromedp.Evaluate(`
-			JSON.stringify(
-				Array.from(document.querySelectorAll('a[href*="/s/"]')).map(link => ({
-					name: link.innerText.trim() || link.getAttribute('aria-label') || 'Unknown',
-					url: link.href
-				})).filter(loc => 
-					loc.url.includes('/s/') && 
-					loc.url.includes('/homes') &&
-					loc.name !== '' &&
-					loc.name !== 'Unknown'
-				).slice(0, 20)
-			)
-		`, &locationsJSON),
-	)
-
+	// Wait for the page to load, then scroll to load more location cards
+	page, doc, err := s.loadDocument(ctx, PageRequest{
+		URL:    s.cfg.BaseURL,
+		Settle: 5 * time.Second,
+		Scroll: true,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to scrape homepage: %w", err)
 	}
 
-	// Parse JSON
-	var rawLocations []struct {
-		Name string `json:"name"`
-		URL  string `json:"url"`
-	}
+	locations := ExtractLocations(doc, page.URL, s.profile)
 
-	if err := json.Unmarshal([]byte(locationsJSON), &rawLocations); err != nil {
-		return nil, fmt.Errorf("failed to parse locations JSON: %w", err)
-	}
+	s.logger.Success("Found %d unique locations on homepage", len(locations))
+
+	return locations, nil
+}
 
-	// Convert to LocationCard structs and deduplicate
+// ExtractLocations reads up to 20 unique search links from the homepage
+func ExtractLocations(doc *dom.Node, pageURL string, profile *SelectorProfile) []LocationCard {
 	locations := []LocationCard{}
 	seen := make(map[string]bool)
+	found := 0
+
+	for _, link := range firstMatchAll(doc, profile.Homepage.LocationLinks) {
+		if found == 20 {
+			break
+		}
+
+		name := link.Text()
+		if name == "" {
+			name = link.AttrOr("aria-label", "Unknown")
+		}
+		url := resolveURL(pageURL, link.AttrOr("href", ""))
+
+		if !strings.Contains(url, "/s/") || !strings.Contains(url, "/homes") ||
+			name == "" || name == "Unknown" {
+			continue
+		}
+		found++
 
-	for _, raw := range rawLocations {
 		// Deduplicate by URL
-		if seen[raw.URL] {
+		if seen[url] {
 			continue
 		}
-		seen[raw.URL] = true
+		seen[url] = true
 
 		locations = append(locations, LocationCard{
-			Name: raw.Name,
-			URL:  raw.URL,
+			Name: name,
+			URL:  url,
 		})
 	}
 
-	s.logger.Success("Found %d unique locations on homepage", len(locations))
-
-	return locations, nil
+	return locations
 }
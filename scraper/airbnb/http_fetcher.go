@@ -0,0 +1,183 @@
+package airbnb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/config"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// maxPageBytes caps how much of a response body the HTTP fetcher reads
+const maxPageBytes = 20 << 20
+
+// HTTPFetcher loads the server-rendered HTML of a page without a browser.
+// It cannot scroll or run scripts, so it only sees what Airbnb renders server-side.
+// A session lasts tab_max_uses requests, like a browser tab, and keeps one
+// fingerprint and one proxy.
+type HTTPFetcher struct {
+	cfg     *config.ScraperConfig
+	logger  *utils.Logger
+	timeout time.Duration
+
+	mu           sync.Mutex
+	fingerprints *FingerprintPool
+	proxies      *ProxyPool
+	maxUses      int
+	session      *httpSession
+}
+
+// httpSession is a run of requests sharing a client, fingerprint and proxy
+type httpSession struct {
+	client      *http.Client
+	fingerprint config.Fingerprint
+	proxy       *Proxy
+	uses        int
+}
+
+// NewHTTPFetcher creates a plain HTTP fetcher. proxies may be nil for direct connections.
+func NewHTTPFetcher(cfg *config.ScraperConfig, logger *utils.Logger, proxies *ProxyPool) *HTTPFetcher {
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	maxUses := cfg.TabMaxUses
+	if maxUses <= 0 {
+		maxUses = defaultTabMaxUses
+	}
+
+	return &HTTPFetcher{
+		cfg:          cfg,
+		logger:       logger,
+		timeout:      timeout,
+		fingerprints: NewFingerprintPool(cfg),
+		proxies:      proxies,
+		maxUses:      maxUses,
+	}
+}
+
+// currentSession returns the session for the next request, starting a new
+// one when the current session is used up
+func (f *HTTPFetcher) currentSession() (*httpSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.session == nil || f.session.uses >= f.maxUses {
+		if err := f.newSession(); err != nil {
+			return nil, err
+		}
+	}
+	f.session.uses++
+	return f.session, nil
+}
+
+// newSession replaces the current session. Callers must hold f.mu.
+func (f *HTTPFetcher) newSession() error {
+	proxy, err := f.proxies.Acquire()
+	if err != nil {
+		return err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != nil {
+		transport.Proxy = http.ProxyURL(proxy.URL)
+	}
+	if f.session != nil {
+		f.session.client.CloseIdleConnections()
+	}
+
+	f.session = &httpSession{
+		client:      &http.Client{Timeout: f.timeout, Transport: transport},
+		fingerprint: f.fingerprints.Next(),
+		proxy:       proxy,
+	}
+
+	if proxy != nil {
+		f.logger.Info("Starting HTTP session with fingerprint %s via proxy %s", f.session.fingerprint.Name, proxy)
+	} else {
+		f.logger.Info("Starting HTTP session with fingerprint %s", f.session.fingerprint.Name)
+	}
+	return nil
+}
+
+// endSession drops a session whose proxy just failed or got blocked so the
+// next request moves to another proxy
+func (f *HTTPFetcher) endSession(session *httpSession) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.session == session {
+		session.uses = f.maxUses
+	}
+}
+
+// Fetch downloads the page HTML
+func (f *HTTPFetcher) Fetch(ctx context.Context, req PageRequest) (*Page, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, req.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request for %s: %w", req.URL, err)
+	}
+
+	session, err := f.currentSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", req.URL, err)
+	}
+
+	fp := session.fingerprint
+	httpReq.Header.Set("User-Agent", fp.UserAgent)
+	httpReq.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	httpReq.Header.Set("Accept-Language", fp.AcceptLanguage)
+
+	resp, err := session.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() == nil {
+			f.proxies.Report(session.proxy, err)
+			f.endSession(session)
+		}
+		return nil, fmt.Errorf("failed to load %s: %w", req.URL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBytes))
+	if err != nil {
+		f.proxies.Report(session.proxy, err)
+		f.endSession(session)
+		return nil, fmt.Errorf("failed to read %s: %w", req.URL, err)
+	}
+
+	page := &Page{
+		URL:         resp.Request.URL.String(),
+		StatusCode:  resp.StatusCode,
+		HTML:        string(body),
+		Fingerprint: fp.Name,
+		retire:      func() { f.endSession(session) },
+	}
+
+	// A blocked or failing session starts over with fresh cookies, like a released tab
+	fault := statusFault(resp.StatusCode)
+	f.proxies.Report(session.proxy, fault)
+	if fault != nil {
+		f.endSession(session)
+	}
+	if resp.StatusCode >= 400 {
+		err = fmt.Errorf("failed to load %s: HTTP %d", req.URL, resp.StatusCode)
+	}
+
+	return page, err
+}
+
+// Close releases idle connections
+func (f *HTTPFetcher) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.session != nil {
+		f.session.client.CloseIdleConnections()
+	}
+	return nil
+}
@@ -0,0 +1,449 @@
+package airbnb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/models"
+	"github.com/farhanasfar/airbnb-market-scraping-system/scraper/dom"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+var (
+	reviewCountPattern  = regexp.MustCompile(`\((\d[\d.,'\x{a0}\x{202f} ]*)\)`)
+	hostResponsePattern = regexp.MustCompile(`(?i)response rate:?\s*(\d+)%`)
+	hostJoinedPattern   = regexp.MustCompile(`(?i)joined in (?:[a-z]+ )?(\d{4})`)
+)
+
+// deferredState returns the decoded JSON of every embedded state block on the page.
+// Blocks are script tags whose id or attributes start with one of the profile's
+// state script prefixes (data-deferred-state-0, data-injector-instances, ...).
+// Numbers are kept as json.Number so long room ids survive intact.
+func deferredState(doc *dom.Node, prefixes []string) []any {
+	var blocks []any
+	for _, script := range doc.Find("script") {
+		if !isStateScript(script, prefixes) {
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader([]byte(script.RawText())))
+		dec.UseNumber()
+
+		var block any
+		if err := dec.Decode(&block); err != nil {
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+func isStateScript(script *dom.Node, prefixes []string) bool {
+	id := script.AttrOr("id", "")
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(id, prefix) {
+			return true
+		}
+		for _, a := range script.Attrs() {
+			if strings.HasPrefix(a.Key, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// walkObjects calls fn for every JSON object below v, parents before children.
+// Keys are visited in sorted order so results come out in a stable order.
+func walkObjects(v any, fn func(map[string]any)) {
+	switch node := v.(type) {
+	case map[string]any:
+		fn(node)
+		keys := make([]string, 0, len(node))
+		for key := range node {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			walkObjects(node[key], fn)
+		}
+	case []any:
+		for _, child := range node {
+			walkObjects(child, fn)
+		}
+	}
+}
+
+// stateCurrency returns the ISO code of the currency the state blocks price in,
+// read from the first "currency" field holding one, or "" when none does
+func stateCurrency(blocks []any) string {
+	currency := ""
+	for _, block := range blocks {
+		walkObjects(block, func(obj map[string]any) {
+			if code, ok := obj["currency"].(string); ok && currency == "" && utils.IsCurrencyCode(code) {
+				currency = code
+			}
+		})
+		if currency != "" {
+			break
+		}
+	}
+	return currency
+}
+
+// lookup follows a path of object keys, returning nil when any step is missing
+func lookup(v any, path ...string) any {
+	for _, key := range path {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = obj[key]
+	}
+	return v
+}
+
+// stringAt returns the value at path as a string; numbers are formatted
+func stringAt(v any, path ...string) string {
+	switch value := lookup(v, path...).(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	}
+	return ""
+}
+
+// numberAt returns the value at path as a float; numeric strings are parsed
+func numberAt(v any, path ...string) float64 {
+	switch value := lookup(v, path...).(type) {
+	case json.Number:
+		f, _ := value.Float64()
+		return f
+	case string:
+		f, _ := strconv.ParseFloat(value, 64)
+		return f
+	}
+	return 0
+}
+
+// firstString returns the first non-empty string found at any of the paths
+func firstString(v any, paths ...[]string) string {
+	for _, path := range paths {
+		if s := stringAt(v, path...); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// roomID normalizes listing ids, which are either numeric or
+// base64 relay ids such as "DemandStayListing:12345"
+func roomID(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	if _, err := strconv.ParseUint(raw, 10, 64); err == nil {
+		return raw
+	}
+	decoded, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return ""
+	}
+	if i := strings.LastIndexByte(string(decoded), ':'); i >= 0 {
+		id := string(decoded[i+1:])
+		if _, err := strconv.ParseUint(id, 10, 64); err == nil {
+			return id
+		}
+	}
+	return ""
+}
+
+// isSearchResult reports whether a state object is one search result card
+func isSearchResult(obj map[string]any) bool {
+	if _, ok := obj["demandStayListing"].(map[string]any); ok {
+		return true
+	}
+	if _, ok := obj["listing"].(map[string]any); ok {
+		_, quoted := obj["pricingQuote"]
+		_, priced := obj["structuredDisplayPrice"]
+		return quoted || priced
+	}
+	return false
+}
+
+// ExtractListingsFromState reads search results from the page's embedded JSON state.
+// It returns nil when the page carries no usable state so callers can fall back to the DOM.
+func ExtractListingsFromState(doc *dom.Node, pageURL string, profile *SelectorProfile) []models.RawListing {
+	listings := []models.RawListing{}
+	seen := make(map[string]bool)
+	locale := PageLocale(doc, pageURL)
+	blocks := deferredState(doc, profile.StateScripts)
+	currency := stateCurrency(blocks)
+
+	for _, block := range blocks {
+		walkObjects(block, func(obj map[string]any) {
+			if !isSearchResult(obj) || len(listings) == 20 {
+				return
+			}
+
+			listing := stateListing(obj, pageURL, locale, currency)
+			if listing.RoomID == "" || seen[listing.RoomID] || listing.Title == "" {
+				return
+			}
+			seen[listing.RoomID] = true
+			listings = append(listings, listing)
+		})
+	}
+
+	if len(listings) == 0 {
+		return nil
+	}
+	return listings
+}
+
+// stateListing converts one search result object into a RawListing. currency is
+// the ISO code the page's state prices in, "" when it names none.
+func stateListing(result map[string]any, pageURL string, locale utils.Locale, currency string) models.RawListing {
+	listing := lookup(result, "listing")
+	demand := lookup(result, "demandStayListing")
+
+	id := roomID(firstString(result,
+		[]string{"listing", "id"},
+		[]string{"demandStayListing", "id"},
+		[]string{"propertyId"}))
+
+	price := firstString(result,
+		[]string{"structuredDisplayPrice", "primaryLine", "discountedPrice"},
+		[]string{"structuredDisplayPrice", "primaryLine", "price"},
+		[]string{"pricingQuote", "structuredStayDisplayPrice", "primaryLine", "discountedPrice"},
+		[]string{"pricingQuote", "structuredStayDisplayPrice", "primaryLine", "price"},
+		[]string{"pricingQuote", "rate", "amountFormatted"})
+	qualifier := firstString(result,
+		[]string{"structuredDisplayPrice", "primaryLine", "qualifier"},
+		[]string{"pricingQuote", "structuredStayDisplayPrice", "primaryLine", "qualifier"})
+	if price != "" && qualifier != "" {
+		price += " " + qualifier
+	}
+
+	rating := firstString(result,
+		[]string{"avgRatingLocalized"},
+		[]string{"listing", "avgRatingLocalized"},
+		[]string{"avgRatingA11yLabel"},
+		[]string{"listing", "avgRating"})
+
+	raw := models.RawListing{
+		RoomID: id,
+		Title: utils.CleanText(firstString(result,
+			[]string{"listing", "name"},
+			[]string{"name"},
+			[]string{"demandStayListing", "description", "name", "localizedStringWithTranslationPreference"},
+			[]string{"subtitle"})),
+		Price: utils.CleanText(price),
+		Location: utils.CleanText(firstString(result,
+			[]string{"listing", "title"},
+			[]string{"title"},
+			[]string{"listing", "city"})),
+		Rating:    utils.CleanText(rating),
+		Currency:  currency,
+		Latitude:  firstNumber(listing, demand, "coordinate", "latitude"),
+		Longitude: firstNumber(listing, demand, "coordinate", "longitude"),
+		Guests:    int(numberAt(listing, "personCapacity")),
+
+		Neighborhood: utils.CleanText(stringAt(listing, "neighborhood")),
+		City: utils.CleanText(firstString(listing,
+			[]string{"localizedCityName"},
+			[]string{"city"})),
+	}
+
+	raw.ReviewCount = int(numberAt(listing, "reviewsCount"))
+	if raw.ReviewCount == 0 {
+		// "4.95 (1,234)", "4,95 (1.234)"
+		if match := reviewCountPattern.FindStringSubmatch(utils.NormalizeDigits(rating)); match != nil {
+			if n := locale.ParseCount(match[1]); n.OK() {
+				raw.ReviewCount = int(n.Value)
+			}
+		}
+	}
+
+	// Dated searches explain the card price with the breakdown the booking panel shows
+	for _, path := range [][]string{{"structuredDisplayPrice"}, {"pricingQuote", "structuredStayDisplayPrice"}} {
+		if rows := statePriceRows(lookup(result, path...)); len(rows) > 0 {
+			raw.Quote = quoteFromRows(rows, locale, pageURL, currency)
+			break
+		}
+	}
+
+	if id != "" {
+		raw.URL = resolveURL(pageURL, "/rooms/"+id)
+	}
+
+	return raw
+}
+
+// firstNumber reads a coordinate field from the classic listing object or
+// from the demandStayListing location block
+func firstNumber(listing, demand any, key, field string) float64 {
+	if v := numberAt(listing, key, field); v != 0 {
+		return v
+	}
+	return numberAt(demand, "location", key, field)
+}
+
+// ExtractDetailsFromState fills a detail result from the page's embedded JSON state.
+// It reports false when the page carries no usable state.
+func ExtractDetailsFromState(doc *dom.Node, result *DetailResult, profile *SelectorProfile) bool {
+	var overview []string
+	found := false
+
+	for _, block := range deferredState(doc, profile.StateScripts) {
+		walkObjects(block, func(obj map[string]any) {
+			if items, ok := obj["overviewItems"].([]any); ok {
+				// The section title names the property type, e.g. "Entire rental unit in Lisbon, Portugal"
+				if title := stringAt(obj, "title"); title != "" {
+					overview = append(overview, title)
+				}
+				for _, item := range items {
+					if title := stringAt(item, "title"); title != "" {
+						overview = append(overview, title)
+					}
+				}
+			}
+
+			// The location section carries the map pin and an address line
+			if _, ok := obj["lat"]; ok && obj["lng"] != nil {
+				if result.Latitude == 0 {
+					result.Latitude = numberAt(obj, "lat")
+					result.Longitude = numberAt(obj, "lng")
+				}
+				if address := firstString(obj, []string{"subtitle"}, []string{"address"}); address != "" {
+					applyAddress(address, result)
+				}
+			}
+
+			stateAmenities(obj, result)
+			stateHost(obj, result)
+			stateHouseRules(obj, result, &overview)
+
+			if result.PropertyType == "" {
+				result.PropertyType = stringAt(obj, "propertyType")
+			}
+			if result.MinNights == 0 {
+				result.MinNights = int(numberAt(obj, "minNights"))
+			}
+			for _, key := range []string{"canInstantBook", "isInstantBookable", "instantBookable"} {
+				if instant, ok := obj[key].(bool); ok && instant {
+					result.InstantBook = true
+				}
+			}
+
+			logging, ok := lookup(obj, "eventDataLogging").(map[string]any)
+			if !ok || stringAt(logging, "listingId") == "" {
+				return
+			}
+			found = true
+			if result.Latitude == 0 {
+				result.Latitude = numberAt(logging, "listingLat")
+				result.Longitude = numberAt(logging, "listingLng")
+			}
+			if result.Guests == 0 {
+				result.Guests = int(numberAt(logging, "personCapacity"))
+			}
+			if result.Rating == 0 {
+				result.Rating = numberAt(logging, "guestSatisfactionOverall")
+			}
+			if result.ReviewCount == 0 {
+				result.ReviewCount = int(numberAt(logging, "visibleReviewCount"))
+			}
+			if result.RoomType == "" {
+				result.RoomType = stringAt(logging, "roomType")
+			}
+			if superhost, ok := logging["isSuperhost"].(bool); ok && superhost {
+				result.Superhost = true
+			}
+		})
+	}
+
+	if len(overview) > 0 {
+		found = true
+		applyDetailPatterns(overview, result, profile, PageLocale(doc, result.URL))
+	}
+
+	return found
+}
+
+// stateAmenities reads the amenity groups of the detail page, skipping the
+// amenities the listing is marked as not having
+func stateAmenities(obj map[string]any, result *DetailResult) {
+	for _, key := range []string{"seeAllAmenitiesGroups", "previewAmenitiesGroups"} {
+		groups, ok := obj[key].([]any)
+		if !ok {
+			continue
+		}
+		for _, group := range groups {
+			amenities, _ := lookup(group, "amenities").([]any)
+			for _, amenity := range amenities {
+				if available, ok := lookup(amenity, "available").(bool); ok && !available {
+					continue
+				}
+				result.Amenities = appendAmenity(result.Amenities, stringAt(amenity, "title"))
+			}
+		}
+	}
+}
+
+// stateHost reads the host card of the detail page
+func stateHost(obj map[string]any, result *DetailResult) {
+	if card, ok := obj["cardData"].(map[string]any); ok && stringAt(card, "userId") != "" {
+		if result.HostID == "" {
+			result.HostID = roomID(stringAt(card, "userId"))
+		}
+		if result.HostName == "" {
+			result.HostName = stringAt(card, "name")
+		}
+		if superhost, ok := card["isSuperhost"].(bool); ok && superhost {
+			result.Superhost = true
+		}
+	}
+	if result.HostID == "" {
+		if id := stringAt(obj, "hostAvatar", "userId"); id != "" {
+			result.HostID = roomID(id)
+		}
+	}
+
+	// hostDetails lists lines such as "Response rate: 100%" and "Joined in 2015"
+	if details, ok := obj["hostDetails"].([]any); ok {
+		for _, detail := range details {
+			text, _ := detail.(string)
+			if m := hostResponsePattern.FindStringSubmatch(text); m != nil && result.HostResponseRate == 0 {
+				result.HostResponseRate, _ = strconv.Atoi(m[1])
+			}
+			if m := hostJoinedPattern.FindStringSubmatch(text); m != nil && result.HostJoinYear == 0 {
+				result.HostJoinYear, _ = strconv.Atoi(m[1])
+			}
+		}
+	}
+}
+
+// stateHouseRules reads the house rules and cancellation policy. The rule
+// titles ("Check-in after 3:00 PM", ...) go to texts for the profile's patterns.
+func stateHouseRules(obj map[string]any, result *DetailResult, texts *[]string) {
+	if rules, ok := obj["houseRules"].([]any); ok {
+		for _, rule := range rules {
+			if title := stringAt(rule, "title"); title != "" {
+				*texts = append(*texts, title)
+			}
+		}
+	}
+	if result.CancellationPolicy == "" {
+		result.CancellationPolicy = firstString(obj,
+			[]string{"cancellationPolicyTitle"},
+			[]string{"cancellationPolicyLabel"},
+		)
+	}
+}
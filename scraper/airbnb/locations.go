@@ -0,0 +1,115 @@
+package airbnb
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/config"
+)
+
+// TargetLocations turns the configured location targets into search pages to scrape,
+// with the shared search filters and each target's own filters applied
+func TargetLocations(baseURL string, locations config.LocationsConfig, today time.Time) ([]LocationCard, error) {
+	cards := make([]LocationCard, 0, len(locations.Targets))
+	for i, target := range locations.Targets {
+		card, err := targetLocation(baseURL, target, locations.Search.Merge(target.Search), today)
+		if err != nil {
+			return nil, fmt.Errorf("location %d: %w", i+1, err)
+		}
+		cards = append(cards, card)
+	}
+	return cards, nil
+}
+
+func targetLocation(baseURL string, target config.LocationTarget, filters config.SearchFilters, today time.Time) (LocationCard, error) {
+	if target.URL != "" {
+		searchURL := resolveURL(baseURL, target.URL)
+		parsed, err := url.Parse(searchURL)
+		if err != nil || parsed.Host == "" {
+			return LocationCard{}, fmt.Errorf("invalid search URL %q", target.URL)
+		}
+
+		name := target.Name
+		if name == "" {
+			name = locationNameFromURL(parsed)
+		}
+		query, err := NewSearchQuery(name, filters, today)
+		if err != nil {
+			return LocationCard{}, err
+		}
+		if searchURL, err = query.Apply(searchURL); err != nil {
+			return LocationCard{}, err
+		}
+		return LocationCard{Name: name, URL: searchURL}, nil
+	}
+
+	place := target.Query
+	if place == "" {
+		place = target.Name
+	}
+	name := target.Name
+	if name == "" {
+		name = place
+	}
+	query, err := NewSearchQuery(place, filters, today)
+	if err != nil {
+		return LocationCard{}, err
+	}
+	return LocationCard{Name: name, URL: query.URL(baseURL)}, nil
+}
+
+// FilterLocations applies search filters to discovered locations
+func FilterLocations(cards []LocationCard, filters config.SearchFilters, today time.Time) ([]LocationCard, error) {
+	filtered := make([]LocationCard, 0, len(cards))
+	for _, card := range cards {
+		query, err := NewSearchQuery(card.Name, filters, today)
+		if err != nil {
+			return nil, err
+		}
+		if card.URL, err = query.Apply(card.URL); err != nil {
+			return nil, err
+		}
+		filtered = append(filtered, card)
+	}
+	return filtered, nil
+}
+
+// locationNameFromURL reads the place name back out of a /s/<place>/homes URL
+func locationNameFromURL(searchURL *url.URL) string {
+	if query := searchURL.Query().Get("query"); query != "" {
+		return query
+	}
+	if place := placeFromPath(searchURL.Path); place != "" {
+		return place
+	}
+	return searchURL.String()
+}
+
+// placeFromPath turns the <place> of a /s/<place>/homes path back into text,
+// e.g. "Paris--France" -> "Paris, France"
+func placeFromPath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "s" || parts[1] == "" {
+		return ""
+	}
+	name := strings.ReplaceAll(parts[1], "--", ", ")
+	return strings.ReplaceAll(name, "-", " ")
+}
+
+// MergeLocations joins location lists in order, dropping repeated search URLs
+func MergeLocations(lists ...[]LocationCard) []LocationCard {
+	merged := []LocationCard{}
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		for _, location := range list {
+			if seen[location.URL] {
+				continue
+			}
+			seen[location.URL] = true
+			merged = append(merged, location)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,131 @@
+package airbnb
+
+import (
+	"net/url"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/scraper/dom"
+)
+
+// pageParams are the search URL parameters that pick a results page.
+// Everything else in a search URL describes the search itself.
+var pageParams = []string{"cursor", "items_offset", "section_offset", "pagination_search"}
+
+// PageURL returns the search URL with its page parameters replaced by params,
+// so every results page can be loaded on its own without visiting the ones before it
+func PageURL(searchURL string, params url.Values) string {
+	parsed, err := url.Parse(searchURL)
+	if err != nil {
+		return ""
+	}
+
+	values := parsed.Query()
+	for _, key := range pageParams {
+		values.Del(key)
+	}
+	for key, value := range params {
+		values[key] = value
+	}
+	parsed.RawQuery = values.Encode()
+	return parsed.String()
+}
+
+// cursorURL returns the search URL for the page an embedded-state cursor points at
+func cursorURL(searchURL, cursor string) string {
+	return PageURL(searchURL, url.Values{"cursor": {cursor}})
+}
+
+// hrefPageParams returns the page parameters of a link, or nil when it has none
+// and so does not point at another results page
+func hrefPageParams(pageURL, href string) url.Values {
+	parsed, err := url.Parse(resolveURL(pageURL, href))
+	if err != nil {
+		return nil
+	}
+
+	query := parsed.Query()
+	params := url.Values{}
+	for _, key := range pageParams {
+		if value, ok := query[key]; ok {
+			params[key] = value
+		}
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
+
+// ExtractNextPageURL returns the URL of the next results page, or "" on the last page.
+// The page cursor comes from the embedded state when it has one, otherwise from the
+// next link's cursor or items_offset parameter. Only that parameter is taken from the
+// link, so a selector matching the wrong link cannot send the crawl somewhere else.
+func ExtractNextPageURL(doc *dom.Node, pageURL string, profile *SelectorProfile) string {
+	if cursor := statePagination(doc, profile).next; cursor != "" {
+		return cursorURL(pageURL, cursor)
+	}
+
+	for _, selector := range profile.Search.NextPage {
+		next := doc.FindFirst(selector)
+		if next == nil {
+			continue
+		}
+		if _, disabled := next.Attr("aria-disabled"); disabled {
+			return ""
+		}
+		if params := hrefPageParams(pageURL, next.AttrOr("href", "")); params != nil {
+			return PageURL(pageURL, params)
+		}
+	}
+	return ""
+}
+
+// ExtractPageURLs returns the URL of every results page the embedded state lists,
+// in page order, or nil when the page carries no page cursors
+func ExtractPageURLs(doc *dom.Node, pageURL string, profile *SelectorProfile) []string {
+	cursors := statePagination(doc, profile).cursors
+	if len(cursors) == 0 {
+		return nil
+	}
+
+	urls := make([]string, len(cursors))
+	for i, cursor := range cursors {
+		urls[i] = cursorURL(pageURL, cursor)
+	}
+	return urls
+}
+
+// pagination is the paging info Airbnb embeds with search results
+type pagination struct {
+	next    string   // cursor of the next page
+	cursors []string // cursors of every page, first page first
+}
+
+// statePagination reads the first paginationInfo object in the embedded state
+func statePagination(doc *dom.Node, profile *SelectorProfile) pagination {
+	var info pagination
+	found := false
+	for _, block := range deferredState(doc, profile.StateScripts) {
+		walkObjects(block, func(obj map[string]any) {
+			if found {
+				return
+			}
+			paging, ok := obj["paginationInfo"].(map[string]any)
+			if !ok {
+				return
+			}
+			found = true
+			info.next = stringAt(paging, "nextPageCursor")
+			if cursors, ok := paging["pageCursors"].([]any); ok {
+				for _, c := range cursors {
+					if cursor, ok := c.(string); ok && cursor != "" {
+						info.cursors = append(info.cursors, cursor)
+					}
+				}
+			}
+		})
+		if found {
+			break
+		}
+	}
+	return info
+}
@@ -0,0 +1,181 @@
+package airbnb
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/config"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// Proxy pool defaults
+const (
+	defaultProxyMaxFailures = 3
+	defaultProxyBench       = 5 * time.Minute
+)
+
+// ErrNoProxyAvailable is returned when every configured proxy is benched
+var ErrNoProxyAvailable = errors.New("all proxies are benched")
+
+// Proxy is one outbound proxy and its health counters
+type Proxy struct {
+	URL *url.URL
+
+	uses         int
+	errors       int
+	consecutive  int // errors since the last success
+	benchedUntil time.Time
+}
+
+// Server returns the proxy address without credentials, as Chrome expects it
+func (p *Proxy) Server() string {
+	return p.URL.Scheme + "://" + p.URL.Host
+}
+
+// String returns the proxy URL with the password masked
+func (p *Proxy) String() string {
+	return p.URL.Redacted()
+}
+
+// ProxyStats summarizes how a proxy was used during the run
+type ProxyStats struct {
+	Proxy   string
+	Uses    int
+	Errors  int
+	Benched bool
+}
+
+// ProxyPool hands out proxies round-robin and benches proxies that keep failing
+type ProxyPool struct {
+	logger      *utils.Logger
+	maxFailures int
+	benchFor    time.Duration
+
+	mu      sync.Mutex
+	proxies []*Proxy
+	next    int
+}
+
+// NewProxyPool parses the proxies in config.
+// It returns nil when no proxies are configured; a nil pool means direct connections.
+func NewProxyPool(cfg *config.ScraperConfig, logger *utils.Logger) (*ProxyPool, error) {
+	if len(cfg.Proxies) == 0 {
+		return nil, nil
+	}
+
+	pool := &ProxyPool{
+		logger:      logger,
+		maxFailures: cfg.ProxyMaxFailures,
+		benchFor:    time.Duration(cfg.ProxyBenchSeconds) * time.Second,
+	}
+	if pool.maxFailures <= 0 {
+		pool.maxFailures = defaultProxyMaxFailures
+	}
+	if pool.benchFor <= 0 {
+		pool.benchFor = defaultProxyBench
+	}
+
+	for _, raw := range cfg.Proxies {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy %q", raw)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q in %s (expected http, https or socks5)", u.Scheme, u.Redacted())
+		}
+		pool.proxies = append(pool.proxies, &Proxy{URL: u})
+	}
+
+	logger.Info("Loaded %d proxies", len(pool.proxies))
+	return pool, nil
+}
+
+// Acquire picks the proxy for a new session, skipping benched proxies
+func (p *ProxyPool) Acquire() (*Proxy, error) {
+	if p == nil {
+		return nil, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for i := 0; i < len(p.proxies); i++ {
+		proxy := p.proxies[p.next]
+		p.next = (p.next + 1) % len(p.proxies)
+
+		if now.Before(proxy.benchedUntil) {
+			continue
+		}
+		return proxy, nil
+	}
+
+	return nil, ErrNoProxyAvailable
+}
+
+// Report records the outcome of a request made through the proxy
+func (p *ProxyPool) Report(proxy *Proxy, err error) {
+	if p == nil || proxy == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	proxy.uses++
+	if err == nil {
+		proxy.consecutive = 0
+		return
+	}
+
+	proxy.errors++
+	proxy.consecutive++
+	if proxy.consecutive >= p.maxFailures {
+		proxy.benchedUntil = time.Now().Add(p.benchFor)
+		proxy.consecutive = 0
+		p.logger.Warning("Benching proxy %s for %s after %d consecutive errors",
+			proxy, p.benchFor, p.maxFailures)
+	}
+}
+
+// statusFault returns the error to report against a proxy for a response status.
+// Failed proxy authentication, blocks and server errors count against the proxy;
+// other statuses, such as a 404 for a removed listing, say nothing about it.
+func statusFault(status int) error {
+	switch {
+	case status == http.StatusProxyAuthRequired,
+		status == http.StatusForbidden,
+		status == http.StatusTooManyRequests,
+		status >= 500:
+		return fmt.Errorf("HTTP %d", status)
+	}
+	return nil
+}
+
+// Stats returns usage and error counts for every proxy
+func (p *ProxyPool) Stats() []ProxyStats {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	stats := make([]ProxyStats, 0, len(p.proxies))
+	for _, proxy := range p.proxies {
+		stats = append(stats, ProxyStats{
+			Proxy:   proxy.String(),
+			Uses:    proxy.uses,
+			Errors:  proxy.errors,
+			Benched: now.Before(proxy.benchedUntil),
+		})
+	}
+	return stats
+}
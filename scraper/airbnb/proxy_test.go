@@ -0,0 +1,125 @@
+package airbnb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/config"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// forwardProxy is a plain HTTP forward proxy that answers for the target
+// itself: /status/<code> gets that status, anything else 200
+type forwardProxy struct {
+	*httptest.Server
+
+	mu   sync.Mutex
+	seen []string
+}
+
+func newForwardProxy(t *testing.T) *forwardProxy {
+	p := &forwardProxy{}
+	p.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		p.seen = append(p.seen, r.URL.String())
+		p.mu.Unlock()
+
+		switch r.URL.Path {
+		case "/status/403":
+			w.WriteHeader(http.StatusForbidden)
+		case "/status/404":
+			w.WriteHeader(http.StatusNotFound)
+		case "/status/503":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write([]byte("<html><body>ok</body></html>"))
+	}))
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *forwardProxy) requests() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.seen)
+}
+
+func TestHTTPFetcherProxies(t *testing.T) {
+	first, second := newForwardProxy(t), newForwardProxy(t)
+	cfg := &config.ScraperConfig{
+		Proxies:          []string{first.URL, second.URL},
+		ProxyMaxFailures: 2,
+		TabMaxUses:       20,
+	}
+	logger := utils.NewLogger()
+	pool, err := NewProxyPool(cfg, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetcher := NewHTTPFetcher(cfg, logger, pool)
+
+	fetch := func(path string) *Page {
+		t.Helper()
+		page, _ := fetcher.Fetch(context.Background(), PageRequest{URL: "http://www.airbnb.test" + path})
+		return page
+	}
+
+	// The session keeps its proxy, and 404s are not the proxy's fault
+	for _, path := range []string{"/", "/rooms/1", "/status/404", "/status/404", "/status/404"} {
+		fetch(path)
+	}
+	if got := first.requests(); got != 5 {
+		t.Fatalf("first proxy saw %d requests, want all 5", got)
+	}
+	if got := first.seen[0]; !strings.HasPrefix(got, "http://www.airbnb.test/") {
+		t.Errorf("proxy got %q, want the absolute target URL", got)
+	}
+	assertStats(t, pool, []ProxyStats{
+		{Proxy: first.URL, Uses: 5},
+		{Proxy: second.URL},
+	})
+
+	// Blocks and server errors count and end the session, so the next request
+	// starts over on the next proxy
+	fetch("/status/403")
+	fetch("/rooms/2")
+	if got := second.requests(); got != 1 {
+		t.Fatalf("second proxy saw %d requests after a block, want 1", got)
+	}
+	fetch("/status/503")
+	assertStats(t, pool, []ProxyStats{
+		{Proxy: first.URL, Uses: 6, Errors: 1},
+		{Proxy: second.URL, Uses: 2, Errors: 1},
+	})
+
+	// Reaching max failures in a row benches the proxy
+	fetch("/status/403")
+	assertStats(t, pool, []ProxyStats{
+		{Proxy: first.URL, Uses: 7, Errors: 2, Benched: true},
+		{Proxy: second.URL, Uses: 2, Errors: 1},
+	})
+
+	// Transport errors count too; with every proxy benched fetches fail
+	second.Close()
+	fetch("/rooms/3")
+	assertStats(t, pool, []ProxyStats{
+		{Proxy: first.URL, Uses: 7, Errors: 2, Benched: true},
+		{Proxy: second.URL, Uses: 3, Errors: 2, Benched: true},
+	})
+	if _, err := fetcher.Fetch(context.Background(), PageRequest{URL: "http://www.airbnb.test/"}); !errors.Is(err, ErrNoProxyAvailable) {
+		t.Errorf("fetch with every proxy benched = %v, want ErrNoProxyAvailable", err)
+	}
+}
+
+func assertStats(t *testing.T, pool *ProxyPool, want []ProxyStats) {
+	t.Helper()
+	if got := pool.Stats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("proxy stats:\n got %+v\nwant %+v", got, want)
+	}
+}
@@ -0,0 +1,176 @@
+package airbnb
+
+import (
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/config"
+	"github.com/farhanasfar/airbnb-market-scraping-system/models"
+	"github.com/farhanasfar/airbnb-market-scraping-system/scraper/dom"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+var (
+	// "5 nights x $120.00", "$120.00 x 5 nights" or "95 € x 2 nuits"
+	quoteNightsPattern = regexp.MustCompile(`(\d+)\s*\p{L}+\s*[x×]\s*(.+)|(.+?)\s*[x×]\s*(\d+)\s*\p{L}+`)
+
+	// A breakdown row's text ends with its amount, e.g. "Cleaning fee $50.00" or
+	// "Reinigungsgebühr 1 234,50 €"
+	quoteRowPattern = regexp.MustCompile(`^(.*?\S)\s+([-−]?(?:[A-Za-z]{0,3}\p{Sc})?\s?\d[\d.,'’\x{a0}\x{202f} ]*(?:\s?(?:\p{Sc}|[A-Za-z]{2,3}))?)$`)
+)
+
+// quoteStay is the stay detail pages are priced for
+type quoteStay struct {
+	DateRange
+	adults int
+}
+
+// newQuoteStay resolves the configured quote stay against today, or returns nil
+// when quotes are disabled
+func newQuoteStay(cfg config.QuoteConfig, today time.Time) (*quoteStay, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	start := cfg.CheckIn
+	if start == "" {
+		start = "+30"
+	}
+	checkIn, err := parseSearchDate(start, today)
+	if err != nil {
+		return nil, err
+	}
+	nights := cfg.Nights
+	if nights <= 0 {
+		nights = 3
+	}
+	adults := cfg.Adults
+	if adults <= 0 {
+		adults = 2
+	}
+
+	return &quoteStay{
+		DateRange: DateRange{CheckIn: checkIn, CheckOut: checkIn.AddDate(0, 0, nights)},
+		adults:    adults,
+	}, nil
+}
+
+// url returns the detail page URL with the stay selected, so the booking panel
+// shows a priced quote
+func (q *quoteStay) url(listingURL string) string {
+	parsed, err := url.Parse(listingURL)
+	if err != nil {
+		return listingURL
+	}
+	values := parsed.Query()
+	values.Set("check_in", q.CheckIn.Format(dateLayout))
+	values.Set("check_out", q.CheckOut.Format(dateLayout))
+	values.Set("adults", strconv.Itoa(q.adults))
+	parsed.RawQuery = values.Encode()
+	return parsed.String()
+}
+
+// ExtractQuote reads the booking panel's price breakdown from a detail page priced
+// for a stay, preferring the embedded state. It returns nil when the page shows none.
+func ExtractQuote(doc *dom.Node, pageURL string, profile *SelectorProfile) *models.PriceQuote {
+	var rows [][2]string // description, amount
+	blocks := deferredState(doc, profile.StateScripts)
+	for _, block := range blocks {
+		walkObjects(block, func(obj map[string]any) {
+			if len(rows) == 0 {
+				rows = statePriceRows(obj)
+			}
+		})
+		if len(rows) > 0 {
+			break
+		}
+	}
+
+	if len(rows) == 0 {
+		for _, el := range firstMatchAll(doc, profile.Detail.QuoteRows) {
+			if match := quoteRowPattern.FindStringSubmatch(utils.NormalizeDigits(utils.CleanText(el.Text()))); match != nil {
+				rows = append(rows, [2]string{match[1], match[2]})
+			}
+		}
+	}
+
+	return quoteFromRows(rows, PageLocale(doc, pageURL), pageURL, stateCurrency(blocks))
+}
+
+// statePriceRows returns the description and amount of every row of the price
+// breakdown explaining obj, or nil when obj has no explanationData
+func statePriceRows(obj any) [][2]string {
+	details, ok := lookup(obj, "explanationData", "priceDetails").([]any)
+	if !ok {
+		return nil
+	}
+	var rows [][2]string
+	for _, group := range details {
+		items, _ := lookup(group, "items").([]any)
+		for _, item := range items {
+			rows = append(rows, [2]string{stringAt(item, "description"), stringAt(item, "priceString")})
+		}
+	}
+	return rows
+}
+
+// quoteFromRows sorts breakdown rows into the quote's fields by their description.
+// The currency is stateCurrency when the page's state names one, else the first
+// amount's, read with utils.PriceCurrency.
+func quoteFromRows(rows [][2]string, locale utils.Locale, pageURL, stateCurrency string) *models.PriceQuote {
+	quote := &models.PriceQuote{}
+	found := false
+	discounts := 0.0
+
+	for _, row := range rows {
+		description := utils.CleanText(row[0])
+		lower := strings.ToLower(description)
+		amount := locale.ParsePrice(row[1]).Value
+		if description == "" || amount == 0 {
+			continue
+		}
+		found = true
+		if quote.Currency == "" {
+			quote.Currency = utils.PriceCurrency(row[1], stateCurrency, locale, pageURL)
+		}
+
+		switch {
+		case strings.Contains(lower, "total"):
+			quote.Total = amount
+		case strings.Contains(lower, "discount") || strings.HasPrefix(strings.TrimSpace(row[1]), "-"):
+			discounts += amount
+		case strings.Contains(lower, "cleaning"):
+			quote.CleaningFee = amount
+		case strings.Contains(lower, "service"):
+			quote.ServiceFee = amount
+		case strings.Contains(lower, "tax"):
+			quote.Taxes = amount
+		default:
+			if match := quoteNightsPattern.FindStringSubmatch(utils.NormalizeDigits(description)); match != nil {
+				nights, rate := match[1], match[2]
+				if nights == "" {
+					nights, rate = match[4], match[3]
+				}
+				quote.Nights, _ = strconv.Atoi(nights)
+				quote.NightlyRate = locale.ParsePrice(rate).Value
+				quote.OriginalPrice = amount
+			}
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	quote.DiscountedPrice = quote.OriginalPrice
+	if quote.OriginalPrice > 0 {
+		quote.DiscountedPrice = math.Max(quote.OriginalPrice-discounts, 0)
+	}
+	if quote.Total == 0 {
+		quote.Total = quote.DiscountedPrice + quote.CleaningFee + quote.ServiceFee + quote.Taxes
+	}
+	return quote
+}
@@ -0,0 +1,230 @@
+package airbnb
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// unsafeFileChars are replaced when turning URL paths into file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// PagePath returns where a page is stored inside a capture directory:
+//
+//	homepage.html           the base URL
+//	rooms/<id>.html         listing detail pages
+//	search/<place>-<h>.html search result pages, <h> hashing the search key
+//	other/<path>-<h>.html   anything else
+//
+// today is the day the run resolves relative dates against; see searchKey.
+func PagePath(dir, rawURL string, today time.Time) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return filepath.Join(dir, "other", hashKey(rawURL)+".html")
+	}
+
+	path := strings.Trim(u.Path, "/")
+	switch {
+	case path == "":
+		return filepath.Join(dir, "homepage.html")
+	case strings.HasPrefix(path, "rooms/"):
+		return filepath.Join(dir, "rooms", fileSafe(strings.TrimPrefix(path, "rooms/"))+".html")
+	case strings.HasPrefix(path, "s/"):
+		name := fileSafe(strings.TrimSuffix(strings.TrimPrefix(path, "s/"), "/homes"))
+		return filepath.Join(dir, "search", name+"-"+hashKey(searchKey(u.Query(), today))+".html")
+	default:
+		return filepath.Join(dir, "other", fileSafe(path)+"-"+hashKey(u.Query().Encode())+".html")
+	}
+}
+
+// searchKey returns the search parameters that identify a saved results page. The
+// stay's dates are replaced by its check-in day counted from today and its nights,
+// so a capture of "+30" or a sweep replays on a later day.
+func searchKey(query url.Values, today time.Time) string {
+	checkIn, inErr := time.Parse(dateLayout, query.Get("checkin"))
+	checkOut, outErr := time.Parse(dateLayout, query.Get("checkout"))
+	if inErr == nil && outErr == nil {
+		day, _ := time.Parse(dateLayout, today.Format(dateLayout))
+		query.Set("checkin", fmt.Sprintf("%+d", DateRange{CheckIn: day, CheckOut: checkIn}.Nights()))
+		query.Set("nights", strconv.Itoa(DateRange{CheckIn: checkIn, CheckOut: checkOut}.Nights()))
+		query.Del("checkout")
+	}
+	return query.Encode()
+}
+
+// metaPath returns where the status and error of a recorded page are kept
+func metaPath(pagePath string) string {
+	return strings.TrimSuffix(pagePath, ".html") + ".meta.json"
+}
+
+// pageMeta is saved next to a recorded page that did not load cleanly, so replay
+// returns it with the same status and error
+type pageMeta struct {
+	URL    string    `json:"url"`
+	Status int       `json:"status"`
+	Class  PageClass `json:"class"` // as far as the status and error tell; challenges are found again in the HTML
+	Error  string    `json:"error,omitempty"`
+}
+
+// replayedError stands in for the error a recorded page was fetched with
+type replayedError struct {
+	msg     string
+	timeout bool
+}
+
+func (e *replayedError) Error() string {
+	return e.msg
+}
+
+// Timeout lets a replayed timeout be classified as one again
+func (e *replayedError) Timeout() bool {
+	return e.timeout
+}
+
+func fileSafe(s string) string {
+	if decoded, err := url.PathUnescape(s); err == nil {
+		s = decoded
+	}
+	return strings.Trim(unsafeFileChars.ReplaceAllString(s, "-"), "-")
+}
+
+func hashKey(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])[:10]
+}
+
+// ReplayFetcher serves pages from a capture directory instead of the network
+type ReplayFetcher struct {
+	dir    string
+	today  time.Time
+	logger *utils.Logger
+}
+
+// NewReplayFetcher creates a fetcher that reads pages saved by a RecordingFetcher
+func NewReplayFetcher(dir string, logger *utils.Logger) *ReplayFetcher {
+	return &ReplayFetcher{
+		dir:    dir,
+		today:  time.Now(),
+		logger: logger,
+	}
+}
+
+// Fetch reads the saved page for the requested URL, with the status and error it
+// was recorded with
+func (f *ReplayFetcher) Fetch(ctx context.Context, req PageRequest) (*Page, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	path := PagePath(f.dir, req.URL, f.today)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no saved page for %s: %w", req.URL, err)
+	}
+
+	f.logger.Info("Replaying %s from %s", req.URL, path)
+	page := &Page{
+		URL:         req.URL,
+		StatusCode:  http.StatusOK,
+		HTML:        string(data),
+		Fingerprint: "replay",
+	}
+
+	metaData, err := os.ReadFile(metaPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return page, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read saved status of %s: %w", req.URL, err)
+	}
+	var meta pageMeta
+	if err := json.Unmarshal(metaData, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse saved status of %s: %w", req.URL, err)
+	}
+	page.StatusCode = meta.Status
+	if meta.Error != "" {
+		return page, &replayedError{msg: meta.Error, timeout: meta.Class == PageTimeout}
+	}
+	return page, nil
+}
+
+// Close is a no-op for replayed pages
+func (f *ReplayFetcher) Close() error {
+	return nil
+}
+
+// RecordingFetcher saves every page loaded by another fetcher into a capture directory
+type RecordingFetcher struct {
+	Fetcher
+	dir    string
+	today  time.Time
+	logger *utils.Logger
+}
+
+// NewRecordingFetcher wraps a fetcher so its pages can be replayed later
+func NewRecordingFetcher(inner Fetcher, dir string, logger *utils.Logger) *RecordingFetcher {
+	return &RecordingFetcher{
+		Fetcher: inner,
+		dir:     dir,
+		today:   time.Now(),
+		logger:  logger,
+	}
+}
+
+// Fetch loads the page and stores it under the requested URL. Pages that come back
+// with an error or a failing status are stored too, with the class and error next
+// to them, so blocks and timeouts replay as they happened.
+func (f *RecordingFetcher) Fetch(ctx context.Context, req PageRequest) (*Page, error) {
+	page, err := f.Fetcher.Fetch(ctx, req)
+	if page == nil || page.HTML == "" {
+		return page, err
+	}
+
+	if recordErr := f.record(req.URL, page, err); recordErr != nil {
+		f.logger.Warning("Failed to record %s: %v", req.URL, recordErr)
+	}
+	return page, err
+}
+
+// record writes the page, and its status and error unless it loaded cleanly
+func (f *RecordingFetcher) record(pageURL string, page *Page, fetchErr error) error {
+	path := PagePath(f.dir, pageURL, f.today)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(page.HTML), 0o644); err != nil {
+		return err
+	}
+
+	class := classifyPage(page, nil, fetchErr, nil)
+	if class == PageOK && page.StatusCode == http.StatusOK {
+		// A page recorded again after an earlier failure drops the old status
+		if err := os.Remove(metaPath(path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	meta := pageMeta{URL: pageURL, Status: page.StatusCode, Class: class}
+	if fetchErr != nil {
+		meta.Error = fetchErr.Error()
+	}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(metaPath(path), data, 0o644)
+}
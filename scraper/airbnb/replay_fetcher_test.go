@@ -0,0 +1,53 @@
+package airbnb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	logger := utils.NewLogger()
+	html := `<html><body><h1>No exact matches</h1></body></html>`
+	timeout := fmt.Errorf("wait selector never appeared: %w", context.DeadlineExceeded)
+
+	recorder := NewRecordingFetcher(stubFetcher{html: html, err: timeout}, dir, logger)
+	recorder.today = recordedOn
+	recorded := "https://www.airbnb.com/s/Porto/homes?adults=2&checkin=2026-11-06&checkout=2026-11-09"
+	if _, err := recorder.Fetch(context.Background(), PageRequest{URL: recorded}); err != timeout {
+		t.Fatalf("recording returned %v, want the fetch error", err)
+	}
+
+	// Replayed a day later, the same "+30" search asks for dates a day later
+	replayer := NewReplayFetcher(dir, logger)
+	replayer.today = recordedOn.AddDate(0, 0, 1)
+	page, err := replayer.Fetch(context.Background(), PageRequest{
+		URL: "https://www.airbnb.com/s/Porto/homes?checkin=2026-11-07&checkout=2026-11-10&adults=2",
+	})
+	if page == nil || page.HTML != html {
+		t.Fatalf("replayed page %+v, want the recorded HTML", page)
+	}
+	if !isTimeout(err) || err.Error() != timeout.Error() {
+		t.Errorf("replayed error %v, want the recorded timeout", err)
+	}
+
+	// A different stay is a different page
+	if _, err := replayer.Fetch(context.Background(), PageRequest{
+		URL: "https://www.airbnb.com/s/Porto/homes?adults=2&checkin=2026-11-14&checkout=2026-11-17",
+	}); err == nil {
+		t.Error("replayed a stay that was never recorded")
+	}
+
+	// Recording the page again once it loads cleanly drops the saved error
+	recorder = NewRecordingFetcher(stubFetcher{html: html}, dir, logger)
+	recorder.today = recordedOn
+	recorder.Fetch(context.Background(), PageRequest{URL: recorded})
+	if _, err := replayer.Fetch(context.Background(), PageRequest{
+		URL: "https://www.airbnb.com/s/Porto/homes?adults=2&checkin=2026-11-07&checkout=2026-11-10",
+	}); err != nil {
+		t.Errorf("replayed a clean recording with error %v", err)
+	}
+}
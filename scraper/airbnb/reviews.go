@@ -0,0 +1,139 @@
+package airbnb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/models"
+	"github.com/farhanasfar/airbnb-market-scraping-system/scraper/dom"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// reviewsPath is the endpoint the listing page's reviews modal pages through.
+// It answers JSON rather than HTML.
+const reviewsPath = "/api/v2/reviews"
+
+// ReviewsURL returns the URL of one page of a listing's guest reviews, newest first
+func ReviewsURL(baseURL, roomID string, offset, limit int, apiKey string) string {
+	values := url.Values{}
+	values.Set("listing_id", roomID)
+	values.Set("role", "guest")
+	values.Set("_order", "recent")
+	values.Set("_offset", strconv.Itoa(offset))
+	values.Set("_limit", strconv.Itoa(limit))
+	if apiKey != "" {
+		values.Set("key", apiKey)
+	}
+	return strings.TrimSuffix(baseURL, "/") + reviewsPath + "?" + values.Encode()
+}
+
+// ScrapeReviews pages through a listing's reviews, newest first, and returns the
+// ones posted on or after since; a zero since returns them all.
+// Paging stops at the first older review, so a listing whose reviews are already
+// stored costs one request. On error nothing is returned: saving only the newest
+// pages would make the next run stop before the reviews that were missed.
+func (s *Scraper) ScrapeReviews(ctx context.Context, listingURL string, since time.Time) ([]models.Review, error) {
+	id := RoomIDFromURL(listingURL)
+	if id == "" {
+		return nil, fmt.Errorf("no room id in %s", listingURL)
+	}
+
+	cfg := s.cfg.Reviews
+	limit := cfg.PageSize
+	if limit <= 0 {
+		limit = 50 // Default
+	}
+
+	var reviews []models.Review
+	for page := 0; cfg.MaxPages <= 0 || page < cfg.MaxPages; page++ {
+		offset := page * limit
+		loaded, doc, err := s.loadDocument(ctx, PageRequest{
+			URL: ReviewsURL(s.cfg.BaseURL, id, offset, limit, s.cfg.APIKey),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load reviews page %d: %w", page+1, err)
+		}
+
+		batch, total, err := ExtractReviews(loaded, doc)
+		if err != nil {
+			return nil, fmt.Errorf("reviews page %d: %w", page+1, err)
+		}
+
+		for _, review := range batch {
+			if !since.IsZero() && !review.ReviewedAt.IsZero() && review.ReviewedAt.Before(since) {
+				return reviews, nil
+			}
+			review.URL = utils.NormalizeURL(listingURL)
+			reviews = append(reviews, review)
+		}
+
+		if len(batch) < limit || (total > 0 && offset+len(batch) >= total) {
+			break
+		}
+	}
+
+	return reviews, nil
+}
+
+// ExtractReviews reads one page of the reviews endpoint's JSON, returning its
+// reviews and the listing's total review count (0 when the answer omits it)
+func ExtractReviews(page *Page, doc *dom.Node) ([]models.Review, int, error) {
+	dec := json.NewDecoder(bytes.NewReader(jsonBody(page, doc)))
+	dec.UseNumber()
+	var data any
+	if err := dec.Decode(&data); err != nil {
+		return nil, 0, fmt.Errorf("invalid reviews JSON: %w", err)
+	}
+
+	items, ok := lookup(data, "reviews").([]any)
+	if !ok {
+		if message := firstString(data, []string{"error_message"}, []string{"error"}); message != "" {
+			return nil, 0, fmt.Errorf("reviews endpoint error: %s", message)
+		}
+		return nil, 0, fmt.Errorf("no reviews in response")
+	}
+
+	reviews := make([]models.Review, 0, len(items))
+	for _, item := range items {
+		review := models.Review{
+			ReviewID: stringAt(item, "id"),
+			ReviewerName: utils.CleanText(firstString(item,
+				[]string{"reviewer", "first_name"},
+				[]string{"reviewer", "firstName"})),
+			Language:     stringAt(item, "language"),
+			Text:         strings.TrimSpace(stringAt(item, "comments")),
+			HostResponse: strings.TrimSpace(stringAt(item, "response")),
+		}
+		if created := firstString(item, []string{"created_at"}, []string{"createdAt"}); created != "" {
+			review.ReviewedAt, _ = time.Parse(time.RFC3339, created)
+		}
+		if review.ReviewID == "" {
+			continue
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, int(numberAt(data, "metadata", "reviews_count")), nil
+}
+
+// jsonBody returns the JSON an API request answered. The HTTP backend returns
+// it as is; browsers wrap it in a <pre> element.
+func jsonBody(page *Page, doc *dom.Node) []byte {
+	if page != nil {
+		if body := strings.TrimSpace(page.HTML); strings.HasPrefix(body, "{") || strings.HasPrefix(body, "[") {
+			return []byte(body)
+		}
+	}
+	if doc != nil {
+		if pre := doc.FindFirst("pre"); pre != nil {
+			return []byte(pre.RawText())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package airbnb
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/config"
+)
+
+// ErrRequestBudgetExhausted is returned once the run has made max_requests requests
+var ErrRequestBudgetExhausted = errors.New("request budget exhausted")
+
+// Block cooldown defaults
+const (
+	defaultBlockCooldown    = 60 * time.Second
+	defaultMaxBlockCooldown = 15 * time.Minute
+)
+
+// Scheduler spaces out every page request of a run.
+// It is shared by all workers, so delays and rate limits apply to the run as a whole.
+type Scheduler struct {
+	minDelay    time.Duration
+	maxDelay    time.Duration
+	interval    time.Duration // minimum gap implied by the requests-per-minute cap
+	maxRequests int
+	cooldown    time.Duration // pause after the first block; doubles with every block in a row
+	maxCooldown time.Duration
+
+	mu          sync.Mutex
+	next        time.Time // earliest start of the next request
+	count       int
+	blocks      int       // blocks since the last successful page
+	pausedUntil time.Time // no request starts before this
+	rng         *rand.Rand
+}
+
+// NewScheduler creates a scheduler from the politeness settings in config.
+// Replayed pages come from disk, so replay runs are not throttled.
+func NewScheduler(cfg *config.ScraperConfig) *Scheduler {
+	s := &Scheduler{
+		rng: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	if cfg.ReplayDir != "" {
+		return s
+	}
+
+	s.minDelay = time.Duration(cfg.DelayMinMs) * time.Millisecond
+	s.maxDelay = time.Duration(cfg.DelayMaxMs) * time.Millisecond
+	if s.maxDelay < s.minDelay {
+		s.maxDelay = s.minDelay
+	}
+	if cfg.RequestsPerMinute > 0 {
+		s.interval = time.Minute / time.Duration(cfg.RequestsPerMinute)
+	}
+	s.maxRequests = cfg.MaxRequests
+
+	s.cooldown = time.Duration(cfg.BlockCooldownSeconds) * time.Second
+	if s.cooldown <= 0 {
+		s.cooldown = defaultBlockCooldown
+	}
+	s.maxCooldown = time.Duration(cfg.BlockCooldownMaxSeconds) * time.Second
+	if s.maxCooldown <= 0 {
+		s.maxCooldown = defaultMaxBlockCooldown
+	}
+	if s.maxCooldown < s.cooldown {
+		s.maxCooldown = s.cooldown
+	}
+
+	return s
+}
+
+// Wait blocks until the next request may start.
+// It fails when the request budget is spent or ctx is cancelled; a cancelled
+// wait gives its slot back, since no request was sent.
+func (s *Scheduler) Wait(ctx context.Context) error {
+	start, err := s.reserve()
+	if err != nil {
+		return err
+	}
+
+	for {
+		delay := time.Until(start)
+		if delay <= 0 {
+			if err := ctx.Err(); err != nil {
+				s.unreserve()
+				return err
+			}
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			s.unreserve()
+			return ctx.Err()
+		}
+
+		// A block may have paused the run while this request was waiting
+		start = s.resumeAt(start)
+	}
+}
+
+// resumeAt pushes start past any cooldown
+func (s *Scheduler) resumeAt(start time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if start.Before(s.pausedUntil) {
+		return s.pausedUntil
+	}
+	return start
+}
+
+// Backoff pauses every request of the run after a block and returns the pause.
+// Each block in a row doubles the pause, up to the configured maximum.
+func (s *Scheduler) Backoff() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.blocks++
+	pause := s.maxCooldown
+	if s.blocks <= 16 {
+		pause = s.cooldown << (s.blocks - 1)
+	}
+	if pause > s.maxCooldown {
+		pause = s.maxCooldown
+	}
+
+	until := time.Now().Add(pause)
+	if until.After(s.pausedUntil) {
+		s.pausedUntil = until
+	}
+	return pause
+}
+
+// ResetBackoff starts the backoff over after a page loads normally
+func (s *Scheduler) ResetBackoff() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blocks = 0
+}
+
+// reserve claims the next request slot and returns when it starts
+func (s *Scheduler) reserve() (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxRequests > 0 && s.count >= s.maxRequests {
+		return time.Time{}, ErrRequestBudgetExhausted
+	}
+	s.count++
+
+	now := time.Now()
+	start := s.next
+	if start.Before(now) {
+		start = now
+	}
+	if start.Before(s.pausedUntil) {
+		start = s.pausedUntil
+	}
+
+	gap := s.minDelay
+	if s.maxDelay > s.minDelay {
+		gap += time.Duration(s.rng.Int63n(int64(s.maxDelay - s.minDelay)))
+	}
+	if gap < s.interval {
+		gap = s.interval
+	}
+	s.next = start.Add(gap)
+
+	return start, nil
+}
+
+// unreserve gives back a slot whose request was never sent
+func (s *Scheduler) unreserve() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count--
+}
+
+// Count returns how many requests have been scheduled so far
+func (s *Scheduler) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// sleepContext waits for d or until ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
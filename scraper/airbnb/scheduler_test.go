@@ -0,0 +1,30 @@
+package airbnb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/config"
+)
+
+func TestSchedulerCancelledWaitKeepsBudget(t *testing.T) {
+	s := NewScheduler(&config.ScraperConfig{RequestsPerMinute: 1, MaxRequests: 2})
+	if err := s.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next slot is a minute away, so both waits give up before it
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		err := s.Wait(ctx)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("wait %d = %v, want context.DeadlineExceeded", i+2, err)
+		}
+	}
+	if got := s.Count(); got != 1 {
+		t.Errorf("count = %d after cancelled waits, want 1", got)
+	}
+}
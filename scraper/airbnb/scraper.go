@@ -2,278 +2,385 @@ package airbnb
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
+	"regexp"
 	"time"
 
-	"github.com/chromedp/chromedp"
 	"github.com/farhanasfar/airbnb-market-scraping-system/config"
 	"github.com/farhanasfar/airbnb-market-scraping-system/models"
+	"github.com/farhanasfar/airbnb-market-scraping-system/scraper/dom"
 	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
 )
 
+var roomPathPattern = regexp.MustCompile(`/rooms/(\d+)`)
+
 // Scraper handles Airbnb scraping operations
 type Scraper struct {
-	cfg    *config.ScraperConfig
-	logger *utils.Logger
+	cfg       *config.ScraperConfig
+	logger    *utils.Logger
+	fetcher   Fetcher
+	profile   *SelectorProfile
+	scheduler *Scheduler
+	proxies   *ProxyPool
+	pages     pageCounter
+	timeout   time.Duration // per page load
+	limits    crawlLimits
+	quote     *quoteStay // stay detail pages are priced for; nil when quotes are off
 }
 
-// NewScraper creates a new Airbnb scraper instance
-func NewScraper(cfg *config.ScraperConfig, logger *utils.Logger) *Scraper {
-	return &Scraper{
-		cfg:    cfg,
-		logger: logger,
+// NewScraper creates a new Airbnb scraper instance using the fetcher and
+// selector profile named in config
+func NewScraper(cfg *config.ScraperConfig, logger *utils.Logger) (*Scraper, error) {
+	profile, err := LoadSelectorProfile(cfg.SelectorProfile)
+	if err != nil {
+		return nil, err
 	}
+	logger.Info("Using selector profile %s", profile.Version)
+
+	limits, err := newCrawlLimits(cfg)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := newQuoteStay(cfg.Quote, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("quote: %w", err)
+	}
+
+	var proxies *ProxyPool
+	if cfg.ReplayDir == "" {
+		proxies, err = NewProxyPool(cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fetcher, err := NewFetcher(cfg, logger, proxies)
+	if err != nil {
+		return nil, err
+	}
+	scraper := newScraper(cfg, logger, fetcher, profile, limits, quote)
+	scraper.proxies = proxies
+	return scraper, nil
 }
 
-// createStealthContext creates a browser context with anti-detection settings
-func (s *Scraper) createStealthContext(parentCtx context.Context) (context.Context, context.CancelFunc) {
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("headless", s.cfg.Headless),
-		chromedp.WindowSize(1440, 900),
-		chromedp.UserAgent("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
-		chromedp.Flag("disable-blink-features", "AutomationControlled"),
-		chromedp.Flag("blink-settings", "imagesEnabled=false"),
-	)
-
-	allocCtx, cancelAlloc := chromedp.NewExecAllocator(parentCtx, opts...)
-	ctx, cancelCtx := chromedp.NewContext(allocCtx)
-
-	return ctx, func() {
-		cancelCtx()
-		cancelAlloc()
+// NewScraperWithFetcher creates a scraper that loads pages through the given fetcher
+func NewScraperWithFetcher(cfg *config.ScraperConfig, logger *utils.Logger, fetcher Fetcher, profile *SelectorProfile) (*Scraper, error) {
+	limits, err := newCrawlLimits(cfg)
+	if err != nil {
+		return nil, err
 	}
+	quote, err := newQuoteStay(cfg.Quote, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("quote: %w", err)
+	}
+	return newScraper(cfg, logger, fetcher, profile, limits, quote), nil
 }
 
-// removeWebdriverProperty removes the webdriver property that sites check
-func removeWebdriverProperty() chromedp.Action {
-	return chromedp.ActionFunc(func(ctx context.Context) error {
-		err := chromedp.Evaluate(`
-			Object.defineProperty(navigator, 'webdriver', {
-				get: () => undefined
-			})
-		`, nil).Do(ctx)
-		return err
-	})
+// newScraper builds a scraper from settings already validated by its callers
+func newScraper(cfg *config.ScraperConfig, logger *utils.Logger, fetcher Fetcher, profile *SelectorProfile, limits crawlLimits, quote *quoteStay) *Scraper {
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	return &Scraper{
+		cfg:       cfg,
+		logger:    logger,
+		fetcher:   fetcher,
+		profile:   profile,
+		scheduler: NewScheduler(cfg),
+		timeout:   timeout,
+		limits:    limits,
+		quote:     quote,
+	}
 }
 
-// ScrapeListings scrapes listings from a specific location URL
-// Returns first PropertiesPerPage listings from each of MaxPages pages
-// Returns first PropertiesPerPage listings from each of MaxPages pages
-func (s *Scraper) ScrapeListings(ctx context.Context, locationURL string) ([]models.RawListing, error) {
-	browserCtx, cancel := s.createStealthContext(ctx)
-	defer cancel()
+// Profile returns the selector profile the scraper extracts with
+func (s *Scraper) Profile() *SelectorProfile {
+	return s.profile
+}
 
-	s.logger.Info("Scraping location: %s", locationURL)
-	s.logger.Info("Target: %d properties per page × %d pages = %d total",
-		s.cfg.PropertiesPerPage, s.cfg.MaxPages, s.cfg.PropertiesPerPage*s.cfg.MaxPages)
+// RequestCount returns how many page requests the scraper has made
+func (s *Scraper) RequestCount() int {
+	return s.scheduler.Count()
+}
 
-	allListings := []models.RawListing{}
+// PageCounts returns how many page loads ended in each class
+func (s *Scraper) PageCounts() map[PageClass]int {
+	return s.pages.snapshot()
+}
 
-	// Navigate to first page
-	err := chromedp.Run(browserCtx,
-		removeWebdriverProperty(),
-		chromedp.Navigate(locationURL),
-		chromedp.WaitVisible(`[data-testid="card-container"]`, chromedp.ByQuery),
-		chromedp.Sleep(3*time.Second),
-	)
+// ProxyStats returns per-proxy usage and error counts, or nil when no proxies are configured
+func (s *Scraper) ProxyStats() []ProxyStats {
+	return s.proxies.Stats()
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to load first page: %w", err)
+// Close releases the fetcher's resources
+func (s *Scraper) Close() error {
+	return s.fetcher.Close()
+}
+
+// loadDocument fetches a page and parses it. Every navigation goes through
+// here so the scheduler can space out requests.
+// The wait selector is checked again on the parsed document because
+// backends without a browser cannot wait for it, and browser backends
+// return what rendered when it timed out.
+// Failed loads return a *LoadError; blocks pause the whole run and may
+// retire the session that was blocked.
+func (s *Scraper) loadDocument(ctx context.Context, req PageRequest) (*Page, *dom.Node, error) {
+	if err := s.scheduler.Wait(ctx); err != nil {
+		return nil, nil, err
 	}
 
-	// Scrape multiple pages
-	for page := 1; page <= s.cfg.MaxPages; page++ {
-		s.logger.Info("Scraping page %d/%d...", page, s.cfg.MaxPages)
-
-		// Extract listings using inline JavaScript
-		var listingsJSON string
-		err := chromedp.Run(browserCtx,
-			chromedp.Sleep(2*time.Second),
-			chromedp.Evaluate(`
-				JSON.stringify(
-					Array.from(document.querySelectorAll('[data-testid="card-container"]')).slice(0, 20).map(card => {
-						const getText = (selector) => {
-							const el = card.querySelector(selector);
-							return el ? el.innerText.trim() : '';
-						};
-						const getAttr = (selector, attr) => {
-							const el = card.querySelector(selector);
-							return el ? el.getAttribute(attr) : '';
-						};
-						return {
-							title: getText('[data-testid="listing-card-subtitle"]') || 
-							       getText('[itemprop="name"]') ||
-							       getText('div[id*="title"]'),
-							price: getText('[data-testid="price-availability-row"]') ||
-							       getText('span._tyxjp1') ||
-							       getText('span[aria-label*="price"]'),
-							location: getText('[data-testid="listing-card-title"]') ||
-							         getText('span[data-testid="listing-card-name"]'),
-							rating: getAttr('[aria-label*="rating"]', 'aria-label') ||
-							       getText('span[aria-label*="rating"]'),
-							url: card.querySelector('a') ? card.querySelector('a').href : '',
-							bedrooms: 0,
-							bathrooms: 0,
-							guests: 0
-						};
-					})
-				)
-			`, &listingsJSON),
-		)
+	// Browser backends stop waiting as soon as a challenge page shows up
+	fetchReq := req
+	if req.WaitSelector != "" && len(s.profile.Block.Challenge) > 0 {
+		fetchReq.WaitSelector = req.WaitSelector + ", " + anyOf(s.profile.Block.Challenge)
+	}
 
-		if err != nil {
-			s.logger.Error("Failed to extract listings from page %d: %v", page, err)
-			continue
+	fetchCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	page, err := s.fetcher.Fetch(fetchCtx, fetchReq)
+	cancel()
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		return nil, nil, err
+	}
+
+	var doc *dom.Node
+	if page != nil {
+		doc = dom.Parse(page.HTML)
+	}
+
+	class := classifyPage(page, doc, err, s.profile)
+	if class == PageTimeout && page != nil && page.HTML != "" {
+		// The page rendered but the wait selector timed out: judge it by what it shows,
+		// so a location without results is not retried as a timeout
+		class = PageOK
+	}
+	if class == PageOK && req.WaitSelector != "" && doc.FindFirst(req.WaitSelector) == nil {
+		class = PageEmpty
+		err = fmt.Errorf("page %s has no %s", req.URL, req.WaitSelector)
+	}
+	s.pages.add(class)
+
+	switch {
+	case class == PageOK:
+		s.scheduler.ResetBackoff()
+		return page, doc, nil
+	case class.Blocked():
+		pause := s.scheduler.Backoff()
+		s.logger.Warning("Blocked (%s) on %s, pausing all requests for %s", class, req.URL, pause)
+		if s.cfg.RotateSessionOnBlock && page != nil {
+			page.retireSession()
+		}
+		if err == nil {
+			err = fmt.Errorf("challenge page served for %s", req.URL)
 		}
+	}
+
+	return page, doc, &LoadError{URL: req.URL, Class: class, Err: err}
+}
 
-		listings := s.parseListingsJSON(listingsJSON)
+// loadSearchPage loads a results page, retrying blocks and timeouts.
+// Blocks have already paused the scheduler, so a retry waits out the cooldown.
+func (s *Scraper) loadSearchPage(ctx context.Context, pageURL string) (*Page, *dom.Node, error) {
+	maxRetries := s.cfg.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = 3 // Default
+	}
 
-		// Limit to PropertiesPerPage (first 5)
-		if len(listings) > s.cfg.PropertiesPerPage {
-			listings = listings[:s.cfg.PropertiesPerPage]
+	var page *Page
+	var doc *dom.Node
+	var err error
+
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		page, doc, err = s.loadDocument(ctx, PageRequest{
+			URL:          pageURL,
+			WaitSelector: anyOf(s.profile.Search.Card),
+			Settle:       3 * time.Second,
+		})
+		class := ClassOf(err)
+		if err == nil || !(class.Blocked() || class == PageTimeout) {
+			break
+		}
+		if attempt < maxRetries {
+			s.logger.Warning("Attempt %d/%d failed for %s: %v. Retrying...", attempt, maxRetries, pageURL, err)
 		}
+	}
 
-		s.logger.Success("Scraped %d listings from page %d (limited to first %d)",
-			len(listings), page, s.cfg.PropertiesPerPage)
-		allListings = append(allListings, listings...)
+	return page, doc, err
+}
 
-		// Navigate to next page if not last
-		if page < s.cfg.MaxPages {
-			time.Sleep(2 * time.Second)
-
-			var hasNext bool
-			s.logger.Info("Looking for 'Next' button...")
-
-			err = chromedp.Run(browserCtx,
-				// Check if next button exists
-				chromedp.Evaluate(`
-					(() => {
-						const nextButton = document.querySelector('a[aria-label="Next"]') ||
-						                   document.querySelector('a[aria-label*="next"]') ||
-						                   document.querySelector('nav a:last-child');
-						return nextButton && !nextButton.getAttribute('aria-disabled');
-					})()
-				`, &hasNext),
-			)
-
-			if err != nil {
-				s.logger.Error("Failed to check for next button: %v", err)
-				break
-			}
+// SearchCursor is where a location's results continue: the page to load next,
+// its number, and the crawl's progress so far
+type SearchCursor struct {
+	URL   string
+	Page  int
+	Found int // unique listings scraped before this page
+	Stale int // pages in a row before this one that brought no new listings
+}
 
-			if !hasNext {
-				s.logger.Info("No 'Next' button found, stopping at page %d", page)
-				break
-			}
+// SearchPageFunc receives each results page once it is scraped, with the cursor of
+// the page after it (nil after the last page). An error stops the location.
+type SearchPageFunc func(page int, listings []models.RawListing, next *SearchCursor) error
 
-			// Click next button
-			err = chromedp.Run(browserCtx,
-				chromedp.Click(`a[aria-label="Next"]`, chromedp.ByQuery),
-			)
-
-			if err != nil {
-				// Try alternative selector
-				s.logger.Warning("First selector failed, trying alternative...")
-				err = chromedp.Run(browserCtx,
-					chromedp.Click(`nav a:last-child`, chromedp.ByQuery),
-				)
-				if err != nil {
-					s.logger.Error("Failed to click next button: %v", err)
-					break
-				}
-			}
+// ScrapeListings scrapes listings from a specific location URL.
+// How many pages and listings it takes depends on the crawl mode.
+func (s *Scraper) ScrapeListings(ctx context.Context, locationURL string) ([]models.RawListing, error) {
+	return s.ScrapeListingsFrom(ctx, SearchCursor{URL: locationURL, Page: 1}, nil)
+}
 
-			s.logger.Success("Clicked 'Next' button")
+// ScrapeListingsFrom scrapes a location's results starting at cursor, so an
+// interrupted location can pick up where it stopped. onPage may be nil.
+// Listings are deduplicated by room id as pages come in; after a resume only
+// the pages scraped since are checked.
+func (s *Scraper) ScrapeListingsFrom(ctx context.Context, cursor SearchCursor, onPage SearchPageFunc) ([]models.RawListing, error) {
+	s.logger.Info("Scraping location: %s", cursor.URL)
+	s.logger.Info("Target: %s", s.limits)
 
-			// Wait for new page to load
-			err = chromedp.Run(browserCtx,
-				chromedp.Sleep(3*time.Second),
-				chromedp.WaitVisible(`[data-testid="card-container"]`, chromedp.ByQuery),
-			)
+	allListings := []models.RawListing{}
+	if s.limits.done(cursor.Page-1, cursor.Found, cursor.Stale) {
+		return allListings, nil
+	}
+	if cursor.Page > 1 {
+		s.logger.Info("Resuming at page %d", cursor.Page)
+	}
 
-			if err != nil {
-				s.logger.Error("Failed waiting for next page: %v", err)
-				break
-			}
+	// Load first page
+	pageURL := cursor.URL
+	page, doc, err := s.loadSearchPage(ctx, pageURL)
+	if ClassOf(err) == PageEmpty {
+		s.logger.Warning("No results on %s", cursor.URL)
+		return allListings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load first page: %w", err)
+	}
 
-			s.logger.Success("Page %d loaded", page+1)
+	// Every page's URL when the state lists page cursors; pageURLs[0] is page 1
+	pageURLs := ExtractPageURLs(doc, pageURL, s.profile)
+
+	seen := make(map[string]bool)
+	visited := map[string]bool{pageURL: true}
+	found, stale := cursor.Found, cursor.Stale
+
+	// Scrape pages until the crawl mode is satisfied or the results run out.
+	// Pages are loaded one at a time even when pageURLs lists them all: the crawl
+	// mode decides after each page whether to go on, checkpoints resume from the
+	// next page's cursor, and the scheduler spaces out every request anyway.
+	// Detail pages are where the run fetches in parallel.
+	for pageNum := cursor.Page; ; pageNum++ {
+		s.logger.Info("Scraping page %d...", pageNum)
+
+		// Prefer the embedded JSON state; card selectors are only a fallback
+		source := "state"
+		listings := ExtractListingsFromState(doc, page.URL, s.profile)
+		if listings == nil {
+			s.logger.Warning("No embedded state on page %d, falling back to card selectors", pageNum)
+			source = "selectors"
+			listings = ExtractListingsFromDOM(doc, page.URL, s.profile)
+		}
+		cards := len(listings)
+
+		// Keep the cards this mode wants, minus room ids already seen
+		listings = s.limits.room(dedupeListings(s.limits.keep(listings), seen), found)
+		found += len(listings)
+		if len(listings) == 0 {
+			stale++
+		} else {
+			stale = 0
 		}
-	}
 
-	s.logger.Success("Total listings scraped for this location: %d", len(allListings))
-	return allListings, nil
-}
+		// Record the search each listing was found by and how the page writes numbers
+		params := SearchParamsFromURL(pageURL)
+		locale := PageLocale(doc, page.URL)
+		for i := range listings {
+			listings[i].SearchParams = params
+			listings[i].Locale = locale.Tag
+		}
 
-// goToNextPage clicks the pagination next button
-func (s *Scraper) goToNextPage(ctx context.Context) (bool, error) {
-	s.logger.Info("Looking for 'Next' button...")
+		s.logger.Success("Scraped %d new listings from page %d (%d cards, %d unique so far)",
+			len(listings), pageNum, cards, found)
+		for _, listing := range listings {
+			s.logger.Info("  [profile %s, %s, fingerprint %s] %s (%s)",
+				s.profile.Version, source, page.Fingerprint, listing.Title, listing.URL)
+		}
+		allListings = append(allListings, listings...)
 
-	var nextButtonExists bool
-	err := chromedp.Evaluate(`
-		(() => {
-			const nextButton = document.querySelector('a[aria-label="Next"]') ||
-			                   document.querySelector('a[aria-label*="next"]') ||
-			                   document.querySelector('nav a:last-child');
-			return nextButton && !nextButton.getAttribute('aria-disabled');
-		})()
-	`, &nextButtonExists).Do(ctx)
+		// Find the next page unless the crawl is done
+		var next *SearchCursor
+		if s.limits.done(pageNum, found, stale) {
+			s.logger.Info("Crawl target reached after page %d", pageNum)
+		} else {
+			s.logger.Info("Looking for the next page cursor...")
 
-	if err != nil {
-		return false, fmt.Errorf("failed to check for next button: %w", err)
-	}
+			nextURL := ExtractNextPageURL(doc, pageURL, s.profile)
+			if nextURL == "" && pageNum < len(pageURLs) {
+				nextURL = pageURLs[pageNum]
+			}
+			switch {
+			case nextURL == "":
+				s.logger.Info("No next page cursor found, stopping at page %d", pageNum)
+			case visited[nextURL]:
+				s.logger.Warning("Next page cursor points back to a page already scraped, stopping at page %d", pageNum)
+			default:
+				visited[nextURL] = true
+				next = &SearchCursor{URL: nextURL, Page: pageNum + 1, Found: found, Stale: stale}
+			}
+		}
 
-	if !nextButtonExists {
-		return false, nil
-	}
+		if onPage != nil {
+			if err := onPage(pageNum, listings, next); err != nil {
+				return allListings, err
+			}
+		}
+		if next == nil {
+			break
+		}
 
-	// Click next button
-	err = chromedp.Click(`a[aria-label="Next"]`, chromedp.ByQuery).Do(ctx)
-	if err != nil {
-		// Try alternative
-		err = chromedp.Click(`nav a:last-child`, chromedp.ByQuery).Do(ctx)
+		// A failed page is retried on its own by its cursor; the pages before it are kept
+		pageURL = next.URL
+		page, doc, err = s.loadSearchPage(ctx, pageURL)
 		if err != nil {
-			return false, fmt.Errorf("failed to click next button: %w", err)
+			return allListings, fmt.Errorf("failed to load page %d: %w", next.Page, err)
 		}
+
+		s.logger.Success("Page %d loaded", next.Page)
 	}
 
-	s.logger.Success("Navigated to next page")
-	return true, nil
+	s.logger.Success("Total listings scraped for this location: %d", len(allListings))
+	return allListings, nil
 }
 
-// parseListingsJSON parses JSON into RawListing structs
-func (s *Scraper) parseListingsJSON(jsonStr string) []models.RawListing {
-	if jsonStr == "" || jsonStr == "[]" || jsonStr == "null" {
-		return []models.RawListing{}
-	}
-
-	var rawData []struct {
-		Title     string `json:"title"`
-		Price     string `json:"price"`
-		Location  string `json:"location"`
-		Rating    string `json:"rating"`
-		URL       string `json:"url"`
-		Bedrooms  int    `json:"bedrooms"`
-		Bathrooms int    `json:"bathrooms"`
-		Guests    int    `json:"guests"`
+// PageLocale returns the locale a page writes numbers in, from its html lang
+// attribute or else its domain
+func PageLocale(doc *dom.Node, pageURL string) utils.Locale {
+	lang := ""
+	if root := doc.FindFirst("html"); root != nil {
+		lang = root.AttrOr("lang", "")
 	}
+	return utils.PageLocale(lang, pageURL)
+}
 
-	if err := json.Unmarshal([]byte(jsonStr), &rawData); err != nil {
-		s.logger.Error("Failed to parse JSON: %v", err)
-		return []models.RawListing{}
+// ExtractListingsFromDOM reads the listing cards from a search results page.
+// pageURL is used to resolve relative listing links.
+func ExtractListingsFromDOM(doc *dom.Node, pageURL string, profile *SelectorProfile) []models.RawListing {
+	cards := firstMatchAll(doc, profile.Search.Card)
+	if len(cards) > 20 {
+		cards = cards[:20]
 	}
 
-	listings := make([]models.RawListing, 0, len(rawData))
-	for _, raw := range rawData {
+	listings := make([]models.RawListing, 0, len(cards))
+	for _, card := range cards {
 		listing := models.RawListing{
-			Title:     utils.CleanText(raw.Title),
-			Price:     utils.CleanText(raw.Price),
-			Location:  utils.CleanText(raw.Location),
-			Rating:    utils.CleanText(raw.Rating),
-			URL:       raw.URL,
-			Bedrooms:  raw.Bedrooms,
-			Bathrooms: raw.Bathrooms,
-			Guests:    raw.Guests,
+			Title:    utils.CleanText(firstValue(card, profile.Search.Title)),
+			Price:    utils.CleanText(firstValue(card, profile.Search.Price)),
+			Location: utils.CleanText(firstValue(card, profile.Search.Location)),
+			Rating:   utils.CleanText(firstValue(card, profile.Search.Rating)),
+		}
+		if href := firstValue(card, profile.Search.Link); href != "" {
+			listing.URL = resolveURL(pageURL, href)
+			listing.RoomID = RoomIDFromURL(listing.URL)
 		}
 
 		if listing.Title != "" && listing.URL != "" {
@@ -283,3 +390,27 @@ func (s *Scraper) parseListingsJSON(jsonStr string) []models.RawListing {
 
 	return listings
 }
+
+// RoomIDFromURL returns the numeric id of a /rooms/<id> URL, or ""
+func RoomIDFromURL(rawURL string) string {
+	if match := roomPathPattern.FindStringSubmatch(rawURL); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// resolveURL makes href absolute against the page it was found on
+func resolveURL(pageURL, href string) string {
+	if href == "" {
+		return ""
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return base.ResolveReference(ref).String()
+}
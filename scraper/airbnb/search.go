@@ -0,0 +1,254 @@
+package airbnb
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/config"
+)
+
+// RoomType is Airbnb's room type filter value
+type RoomType string
+
+const (
+	RoomEntireHome  RoomType = "Entire home/apt"
+	RoomPrivateRoom RoomType = "Private room"
+	RoomSharedRoom  RoomType = "Shared room"
+	RoomHotelRoom   RoomType = "Hotel room"
+)
+
+// roomTypes maps config names to room types
+var roomTypes = map[string]RoomType{
+	"entire_home":  RoomEntireHome,
+	"private_room": RoomPrivateRoom,
+	"shared_room":  RoomSharedRoom,
+	"hotel_room":   RoomHotelRoom,
+}
+
+// amenityIDs maps config names to Airbnb's amenity filter ids.
+// Other amenities can be configured by id.
+var amenityIDs = map[string]int{
+	"wifi":                4,
+	"air_conditioning":    5,
+	"pool":                7,
+	"kitchen":             8,
+	"free_parking":        9,
+	"gym":                 15,
+	"hot_tub":             25,
+	"heating":             30,
+	"washer":              33,
+	"dryer":               34,
+	"dedicated_workspace": 47,
+	"tv":                  58,
+	"ev_charger":          97,
+}
+
+// searchParamKeys are the query parameters that define a search
+var searchParamKeys = []string{
+	"query", "checkin", "checkout", "adults", "children", "infants", "pets",
+	"price_min", "price_max", "room_types[]", "min_bedrooms", "amenities[]",
+}
+
+const dateLayout = "2006-01-02"
+
+// SearchQuery is a homes search for a place, with optional filters.
+// Zero values leave a filter unset.
+type SearchQuery struct {
+	Place       string
+	CheckIn     time.Time
+	CheckOut    time.Time
+	Adults      int
+	Children    int
+	Infants     int
+	Pets        int
+	PriceMin    int
+	PriceMax    int
+	RoomType    RoomType
+	MinBedrooms int
+	Amenities   []int
+}
+
+// NewSearchQuery builds the search for place from configured filters.
+// Relative dates are resolved against today.
+func NewSearchQuery(place string, filters config.SearchFilters, today time.Time) (SearchQuery, error) {
+	q := SearchQuery{
+		Place:       strings.Join(strings.Fields(place), " "),
+		Adults:      filters.Adults,
+		Children:    filters.Children,
+		Infants:     filters.Infants,
+		Pets:        filters.Pets,
+		PriceMin:    filters.PriceMin,
+		PriceMax:    filters.PriceMax,
+		MinBedrooms: filters.MinBedrooms,
+	}
+
+	if filters.CheckIn != "" {
+		checkIn, err := parseSearchDate(filters.CheckIn, today)
+		if err != nil {
+			return q, fmt.Errorf("check_in: %w", err)
+		}
+		q.CheckIn = checkIn
+
+		switch {
+		case filters.CheckOut != "":
+			checkOut, err := parseSearchDate(filters.CheckOut, today)
+			if err != nil {
+				return q, fmt.Errorf("check_out: %w", err)
+			}
+			q.CheckOut = checkOut
+		case filters.Nights > 0:
+			q.CheckOut = checkIn.AddDate(0, 0, filters.Nights)
+		default:
+			return q, fmt.Errorf("check_in needs check_out or nights")
+		}
+		if !q.CheckOut.After(q.CheckIn) {
+			return q, fmt.Errorf("check_out %s is not after check_in %s",
+				q.CheckOut.Format(dateLayout), q.CheckIn.Format(dateLayout))
+		}
+	} else if filters.CheckOut != "" || filters.Nights > 0 {
+		return q, fmt.Errorf("check_out and nights need check_in")
+	}
+
+	if q.PriceMin > 0 && q.PriceMax > 0 && q.PriceMin > q.PriceMax {
+		return q, fmt.Errorf("price_min %d is above price_max %d", q.PriceMin, q.PriceMax)
+	}
+
+	if filters.RoomType != "" {
+		roomType, ok := roomTypes[strings.ToLower(filters.RoomType)]
+		if !ok {
+			return q, fmt.Errorf("unknown room_type %q", filters.RoomType)
+		}
+		q.RoomType = roomType
+	}
+
+	for _, amenity := range filters.Amenities {
+		id, ok := amenityIDs[strings.ToLower(amenity)]
+		if !ok {
+			n, err := strconv.Atoi(amenity)
+			if err != nil {
+				return q, fmt.Errorf("unknown amenity %q (use a name like wifi or an Airbnb amenity id)", amenity)
+			}
+			id = n
+		}
+		q.Amenities = append(q.Amenities, id)
+	}
+
+	return q, nil
+}
+
+// parseSearchDate reads YYYY-MM-DD, "+N" days from today, or a weekday name
+// meaning the next such day after today
+func parseSearchDate(value string, today time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+
+	if strings.HasPrefix(value, "+") {
+		days, err := strconv.Atoi(value[1:])
+		if err != nil || days < 0 {
+			return time.Time{}, fmt.Errorf("invalid relative date %q", value)
+		}
+		return today.AddDate(0, 0, days), nil
+	}
+
+	if day, ok := parseWeekday(value); ok {
+		days := (int(day) - int(today.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return today.AddDate(0, 0, days), nil
+	}
+
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (use YYYY-MM-DD, +N or a weekday)", value)
+	}
+	return date, nil
+}
+
+// URL builds the /s/<place>/homes search page for the query.
+// Airbnb puts the place in the path with ", " as "--" and spaces as "-",
+// e.g. "Lisbon, Portugal" -> /s/Lisbon--Portugal/homes?query=Lisbon%2C+Portugal
+func (q SearchQuery) URL(baseURL string) string {
+	slug := strings.ReplaceAll(q.Place, ", ", "--")
+	slug = strings.ReplaceAll(slug, ",", "--")
+	slug = strings.ReplaceAll(slug, " ", "-")
+
+	values := q.Params()
+	values.Set("query", q.Place)
+	return strings.TrimSuffix(baseURL, "/") + "/s/" + url.PathEscape(slug) + "/homes?" + values.Encode()
+}
+
+// Apply sets the query's filters on an existing search URL, replacing any it already has
+func (q SearchQuery) Apply(searchURL string) (string, error) {
+	parsed, err := url.Parse(searchURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid search URL %q: %w", searchURL, err)
+	}
+
+	values := parsed.Query()
+	for key, value := range q.Params() {
+		values[key] = value
+	}
+	parsed.RawQuery = values.Encode()
+	return parsed.String(), nil
+}
+
+// Params returns the query's filters as search URL parameters
+func (q SearchQuery) Params() url.Values {
+	values := url.Values{}
+	if !q.CheckIn.IsZero() {
+		values.Set("checkin", q.CheckIn.Format(dateLayout))
+		values.Set("checkout", q.CheckOut.Format(dateLayout))
+	}
+	setCount := func(key string, n int) {
+		if n > 0 {
+			values.Set(key, strconv.Itoa(n))
+		}
+	}
+	setCount("adults", q.Adults)
+	setCount("children", q.Children)
+	setCount("infants", q.Infants)
+	setCount("pets", q.Pets)
+	setCount("price_min", q.PriceMin)
+	setCount("price_max", q.PriceMax)
+	setCount("min_bedrooms", q.MinBedrooms)
+	if q.RoomType != "" {
+		values.Set("room_types[]", string(q.RoomType))
+	}
+	for _, id := range q.Amenities {
+		values.Add("amenities[]", strconv.Itoa(id))
+	}
+	return values
+}
+
+// SearchParamsFromURL returns the search parameters of a results page URL,
+// leaving out paging and tracking parameters. Repeated parameters are joined with ",".
+func SearchParamsFromURL(pageURL string) map[string]string {
+	parsed, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	values := parsed.Query()
+	params := make(map[string]string)
+	for _, key := range searchParamKeys {
+		if v := values[key]; len(v) > 0 {
+			v = append([]string(nil), v...)
+			sort.Strings(v)
+			params[key] = strings.Join(v, ",")
+		}
+	}
+	if params["query"] == "" {
+		if place := placeFromPath(parsed.Path); place != "" {
+			params["query"] = place
+		}
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
@@ -0,0 +1,257 @@
+package airbnb
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/scraper/dom"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// RuleCheck is the outcome of one selector or pattern
+type RuleCheck struct {
+	Rule    string
+	Matches int
+	Sample  string
+}
+
+// FieldCheck is the outcome of every rule for one extracted field
+type FieldCheck struct {
+	Page     string
+	Field    string
+	Required bool
+	Rules    []RuleCheck
+}
+
+// OK reports whether any rule for the field matched
+func (f FieldCheck) OK() bool {
+	for _, r := range f.Rules {
+		if r.Matches > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectorReport lists which extraction rules still match live pages
+type SelectorReport struct {
+	Version string
+	Pages   map[string]string // page kind -> URL checked
+	Fields  []FieldCheck
+}
+
+// Failed returns the required fields that no rule matched
+func (r *SelectorReport) Failed() []FieldCheck {
+	var failed []FieldCheck
+	for _, f := range r.Fields {
+		if f.Required && !f.OK() {
+			failed = append(failed, f)
+		}
+	}
+	return failed
+}
+
+// CheckSelectors loads one homepage, one search page and one detail page and
+// tries every rule of the selector profile against them
+func (s *Scraper) CheckSelectors(ctx context.Context) (*SelectorReport, error) {
+	p := s.profile
+	report := &SelectorReport{
+		Version: p.Version,
+		Pages:   make(map[string]string),
+	}
+
+	// Homepage
+	s.logger.Info("Checking homepage selectors...")
+	page, doc, err := s.loadDocument(ctx, PageRequest{URL: s.cfg.BaseURL, Settle: 5 * time.Second, Scroll: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load homepage: %w", err)
+	}
+	report.Pages["homepage"] = page.URL
+	report.Fields = append(report.Fields,
+		checkElements("homepage", "location_links", true, doc, p.Homepage.LocationLinks))
+
+	locations := ExtractLocations(doc, page.URL, p)
+	if len(locations) == 0 {
+		report.Fields = append(report.Fields, unreachable("search"), unreachable("detail"))
+		return report, nil
+	}
+
+	// Search page
+	s.logger.Info("Checking search page selectors on %s...", locations[0].Name)
+	page, doc, err = s.loadDocument(ctx, PageRequest{URL: locations[0].URL, Settle: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load search page: %w", err)
+	}
+	report.Pages["search"] = page.URL
+
+	cards := firstMatchAll(doc, p.Search.Card)
+	listings := ExtractListingsFromState(doc, page.URL, p)
+	report.Fields = append(report.Fields,
+		checkElements("search", "card", true, doc, p.Search.Card),
+		checkValues("search", "title", true, cards, p.Search.Title),
+		checkValues("search", "price", true, cards, p.Search.Price),
+		checkValues("search", "location", false, cards, p.Search.Location),
+		checkValues("search", "rating", false, cards, p.Search.Rating),
+		checkValues("search", "link", true, cards, p.Search.Link),
+		checkElements("search", "next_page", false, doc, p.Search.NextPage),
+		checkState("search", doc, p, len(listings)),
+	)
+
+	if listings == nil {
+		listings = ExtractListingsFromDOM(doc, page.URL, p)
+	}
+	if len(listings) == 0 {
+		report.Fields = append(report.Fields, unreachable("detail"))
+		return report, nil
+	}
+
+	// Detail page
+	detailURL := utils.NormalizeURL(listings[0].URL)
+	s.logger.Info("Checking detail page selectors on %s...", detailURL)
+	page, doc, err = s.loadDocument(ctx, PageRequest{URL: detailURL, Settle: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load detail page: %w", err)
+	}
+	report.Pages["detail"] = page.URL
+
+	var texts []string
+	for _, el := range firstMatchAll(doc, p.Detail.Text) {
+		texts = append(texts, el.Text())
+	}
+	detailState := 0
+	if ExtractDetailsFromState(doc, &DetailResult{}, p) {
+		detailState = 1
+	}
+	report.Fields = append(report.Fields,
+		checkElements("detail", "ready", true, doc, p.Detail.Ready),
+		checkElements("detail", "amenities", false, doc, p.Detail.Amenities),
+		checkElements("detail", "address", false, doc, p.Detail.Address),
+		checkElements("detail", "text", true, doc, p.Detail.Text),
+	)
+	// Only the room counts are on every listing; the rest depend on the host
+	for _, field := range p.Detail.patternFields() {
+		required := field.name == "bedrooms" || field.name == "bathrooms" || field.name == "guests"
+		report.Fields = append(report.Fields, checkPatterns("detail", field.name, required, texts, p.patterns[field.name]))
+	}
+	report.Fields = append(report.Fields, checkState("detail", doc, p, detailState))
+
+	return report, nil
+}
+
+// checkElements counts the elements each selector finds on the page
+func checkElements(page, field string, required bool, doc *dom.Node, rules []string) FieldCheck {
+	check := FieldCheck{Page: page, Field: field, Required: required}
+	for _, rule := range rules {
+		selector, attr := splitRule(rule)
+		found := doc.Find(selector)
+
+		rc := RuleCheck{Rule: rule, Matches: len(found)}
+		if len(found) > 0 {
+			rc.Sample = sampleOf(found[0], attr)
+		}
+		check.Rules = append(check.Rules, rc)
+	}
+	return check
+}
+
+// checkValues counts the cards in which each rule yields a value
+func checkValues(page, field string, required bool, cards []*dom.Node, rules []string) FieldCheck {
+	check := FieldCheck{Page: page, Field: field, Required: required}
+	for _, rule := range rules {
+		rc := RuleCheck{Rule: rule}
+		for _, card := range cards {
+			value := firstValue(card, []string{rule})
+			if value == "" {
+				continue
+			}
+			rc.Matches++
+			if rc.Sample == "" {
+				rc.Sample = value
+			}
+		}
+		check.Rules = append(check.Rules, rc)
+	}
+	return check
+}
+
+// checkPatterns counts the texts each pattern matches
+func checkPatterns(page, field string, required bool, texts []string, patterns []*regexp.Regexp) FieldCheck {
+	check := FieldCheck{Page: page, Field: field, Required: required}
+	for _, pattern := range patterns {
+		rc := RuleCheck{Rule: pattern.String()}
+		for _, text := range texts {
+			match := pattern.FindString(text)
+			if match == "" {
+				continue
+			}
+			rc.Matches++
+			if rc.Sample == "" {
+				rc.Sample = match
+			}
+		}
+		check.Rules = append(check.Rules, rc)
+	}
+	return check
+}
+
+// checkState reports how many state blocks the page has and how many records they yielded
+func checkState(page string, doc *dom.Node, p *SelectorProfile, records int) FieldCheck {
+	blocks := len(deferredState(doc, p.StateScripts))
+	return FieldCheck{
+		Page:  page,
+		Field: "state",
+		Rules: []RuleCheck{{
+			Rule:    fmt.Sprintf("%v", p.StateScripts),
+			Matches: records,
+			Sample:  fmt.Sprintf("%d blocks", blocks),
+		}},
+	}
+}
+
+// unreachable marks a page that could not be checked because no link led to it
+func unreachable(page string) FieldCheck {
+	return FieldCheck{Page: page, Field: "page", Required: true}
+}
+
+func sampleOf(el *dom.Node, attr string) string {
+	value := el.Text()
+	if attr != "" {
+		value = el.AttrOr(attr, "")
+	}
+	if runes := []rune(value); len(runes) > 60 {
+		value = string(runes[:57]) + "..."
+	}
+	return value
+}
+
+// Print writes the report to the logger
+func (r *SelectorReport) Print(logger *utils.Logger) {
+	logger.Info("\nSELECTOR HEALTH CHECK (profile %s)", r.Version)
+	for _, kind := range []string{"homepage", "search", "detail"} {
+		if url, ok := r.Pages[kind]; ok {
+			logger.Info("   %-9s %s", kind+":", url)
+		}
+	}
+
+	for _, f := range r.Fields {
+		status := "✓"
+		if !f.OK() {
+			status = "✗"
+			if !f.Required {
+				status = "⚠"
+			}
+		}
+		logger.Info("\n %s %s.%s", status, f.Page, f.Field)
+		for _, rc := range f.Rules {
+			logger.Info("      %-45s %4d  %s", rc.Rule, rc.Matches, rc.Sample)
+		}
+	}
+
+	if failed := r.Failed(); len(failed) > 0 {
+		logger.Error("%d required fields no longer match", len(failed))
+	} else {
+		logger.Success("All required fields matched")
+	}
+}
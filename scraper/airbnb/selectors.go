@@ -0,0 +1,320 @@
+package airbnb
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/scraper/dom"
+	"gopkg.in/yaml.v3"
+)
+
+// SelectorProfile holds every selector and pattern the extractors use.
+// Each field lists rules in fallback order; the first rule that yields a value wins.
+// A rule is a CSS selector, optionally followed by "@attr" to read an attribute
+// instead of the element text, e.g. `[aria-label*="rating"]@aria-label`.
+type SelectorProfile struct {
+	Version string `yaml:"version"`
+
+	// StateScripts are id/attribute prefixes of the embedded JSON state blocks
+	StateScripts []string `yaml:"state_scripts"`
+
+	Homepage HomepageSelectors `yaml:"homepage"`
+	Search   SearchSelectors   `yaml:"search"`
+	Detail   DetailSelectors   `yaml:"detail"`
+	Block    BlockSelectors    `yaml:"block"`
+
+	// compiled detail patterns, by field name
+	patterns map[string][]*regexp.Regexp
+
+	// compiled challenge text patterns
+	challengeText []*regexp.Regexp
+}
+
+type HomepageSelectors struct {
+	LocationLinks []string `yaml:"location_links"`
+}
+
+type SearchSelectors struct {
+	Card     []string `yaml:"card"`
+	Title    []string `yaml:"title"`
+	Price    []string `yaml:"price"`
+	Location []string `yaml:"location"`
+	Rating   []string `yaml:"rating"`
+	Link     []string `yaml:"link"`
+	NextPage []string `yaml:"next_page"`
+}
+
+type DetailSelectors struct {
+	Ready []string `yaml:"ready"`
+
+	// Amenities selects the amenity list items
+	Amenities []string `yaml:"amenities"`
+
+	// Address selects the location section's address line, e.g. "Lisbon, Lisbon, Portugal"
+	Address []string `yaml:"address"`
+
+	// QuoteRows selects the booking panel's price breakdown rows, e.g. "Cleaning fee $50"
+	QuoteRows []string `yaml:"quote_rows"`
+
+	// Text selects the elements whose text the patterns are matched against.
+	// Each pattern's first capture group is the value.
+	Text               []string `yaml:"text"`
+	Bedrooms           []string `yaml:"bedrooms"`
+	Bathrooms          []string `yaml:"bathrooms"`
+	Guests             []string `yaml:"guests"`
+	Beds               []string `yaml:"beds"`
+	PropertyType       []string `yaml:"property_type"`
+	HostName           []string `yaml:"host_name"`
+	Superhost          []string `yaml:"superhost"`
+	HostJoined         []string `yaml:"host_joined"`
+	ResponseRate       []string `yaml:"response_rate"`
+	CheckIn            []string `yaml:"check_in"`
+	CheckOut           []string `yaml:"check_out"`
+	MinNights          []string `yaml:"min_nights"`
+	CancellationPolicy []string `yaml:"cancellation_policy"`
+	InstantBook        []string `yaml:"instant_book"`
+}
+
+// patternFields lists the detail patterns by field name, in the order they are checked
+func (d *DetailSelectors) patternFields() []struct {
+	name  string
+	rules []string
+} {
+	return []struct {
+		name  string
+		rules []string
+	}{
+		{"bedrooms", d.Bedrooms},
+		{"bathrooms", d.Bathrooms},
+		{"guests", d.Guests},
+		{"beds", d.Beds},
+		{"property_type", d.PropertyType},
+		{"host_name", d.HostName},
+		{"superhost", d.Superhost},
+		{"host_joined", d.HostJoined},
+		{"response_rate", d.ResponseRate},
+		{"check_in", d.CheckIn},
+		{"check_out", d.CheckOut},
+		{"min_nights", d.MinNights},
+		{"cancellation_policy", d.CancellationPolicy},
+		{"instant_book", d.InstantBook},
+	}
+}
+
+// BlockSelectors recognize the pages Airbnb serves instead of content when it blocks a client
+type BlockSelectors struct {
+	// Challenge matches elements found only on CAPTCHA and bot check pages
+	Challenge []string `yaml:"challenge"`
+
+	// ChallengeText are regular expressions matched against the page title and text
+	ChallengeText []string `yaml:"challenge_text"`
+}
+
+// DefaultSelectorProfile returns the built-in profile used when config names no profile file
+func DefaultSelectorProfile() *SelectorProfile {
+	p := &SelectorProfile{
+		Version:      "builtin",
+		StateScripts: []string{"data-deferred-state", "data-injector-instances"},
+		Homepage: HomepageSelectors{
+			LocationLinks: []string{`a[href*="/s/"]`},
+		},
+		Search: SearchSelectors{
+			Card:     []string{`[data-testid="card-container"]`},
+			Title:    []string{`[data-testid="listing-card-subtitle"]`, `[itemprop="name"]`, `div[id*="title"]`},
+			Price:    []string{`[data-testid="price-availability-row"]`, `span._tyxjp1`, `span[aria-label*="price"]`},
+			Location: []string{`[data-testid="listing-card-title"]`, `span[data-testid="listing-card-name"]`},
+			Rating:   []string{`[aria-label*="rating"]@aria-label`, `span[aria-label*="rating"]`},
+			Link:     []string{`a@href`},
+			NextPage: []string{`a[aria-label="Next"]`, `a[aria-label*="next"]`},
+		},
+		Detail: DetailSelectors{
+			Ready:              []string{`[data-section-id="OVERVIEW_DEFAULT"]`},
+			Amenities:          []string{`[data-section-id="AMENITIES_DEFAULT"] li`},
+			Address:            []string{`[data-section-id="LOCATION_DEFAULT"] h3`},
+			QuoteRows:          []string{`[data-section-id="BOOK_IT_SIDEBAR"] [data-testid="price-item"]`},
+			Text:               []string{`li, span, div`},
+			Bedrooms:           []string{`(?i)(\d+)\s*(bedroom|bed)`},
+			Bathrooms:          []string{`(?i)(\d+\.?\d*)\s*bath`},
+			Guests:             []string{`(?i)(\d+)\s*guest`},
+			Beds:               []string{`(?i)(\d+)\s*beds?\b`},
+			PropertyType:       []string{`^((?:Entire|Private|Shared|Room in)[A-Za-z ]*?) in \p{Lu}`},
+			HostName:           []string{`(?i)hosted by ([^·\n]+?)\s*(?:·|$)`},
+			Superhost:          []string{`(?i)\bis a (superhost)\b`},
+			HostJoined:         []string{`(?i)joined in (?:[a-z]+ )?(\d{4})`},
+			ResponseRate:       []string{`(?i)response rate:?\s*(\d+)%`},
+			CheckIn:            []string{`(?i)check-?in(?: after| from)?:?\s*(\d{1,2}(?::\d{2})?\s*[ap]m)`},
+			CheckOut:           []string{`(?i)check-?out(?: before)?:?\s*(\d{1,2}(?::\d{2})?\s*[ap]m)`},
+			MinNights:          []string{`(?i)(\d+)[- ]night minimum`},
+			CancellationPolicy: []string{`(?i)(free cancellation (?:before|for) [^.·]+)`, `(?i)(non-refundable)`},
+			InstantBook:        []string{`(?i)(instant book)`},
+		},
+		Block: BlockSelectors{
+			Challenge:     []string{`#px-captcha`, `iframe[src*="captcha"]`},
+			ChallengeText: []string{`(?i)verify you are (a )?human`, `(?i)press (&|and) hold`, `(?i)access to this page has been denied`},
+		},
+	}
+	if err := p.compile(); err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// LoadSelectorProfile reads a selector profile file, or returns the built-in
+// profile when path is empty
+func LoadSelectorProfile(path string) (*SelectorProfile, error) {
+	if path == "" {
+		return DefaultSelectorProfile(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read selector profile: %w", err)
+	}
+
+	var p SelectorProfile
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse selector profile: %w", err)
+	}
+
+	if p.Version == "" {
+		return nil, fmt.Errorf("selector profile %s has no version", path)
+	}
+	if err := p.compile(); err != nil {
+		return nil, fmt.Errorf("invalid selector profile %s: %w", path, err)
+	}
+
+	return &p, nil
+}
+
+// compile validates every rule and prepares the detail patterns
+func (p *SelectorProfile) compile() error {
+	required := map[string][]string{
+		"homepage.location_links": p.Homepage.LocationLinks,
+		"search.card":             p.Search.Card,
+		"search.title":            p.Search.Title,
+		"search.link":             p.Search.Link,
+		"detail.ready":            p.Detail.Ready,
+		"detail.text":             p.Detail.Text,
+	}
+	for field, rules := range required {
+		if len(rules) == 0 {
+			return fmt.Errorf("%s needs at least one rule", field)
+		}
+	}
+
+	for field, rules := range map[string][]string{
+		"homepage.location_links": p.Homepage.LocationLinks,
+		"search.card":             p.Search.Card,
+		"search.title":            p.Search.Title,
+		"search.price":            p.Search.Price,
+		"search.location":         p.Search.Location,
+		"search.rating":           p.Search.Rating,
+		"search.link":             p.Search.Link,
+		"search.next_page":        p.Search.NextPage,
+		"detail.ready":            p.Detail.Ready,
+		"detail.amenities":        p.Detail.Amenities,
+		"detail.address":          p.Detail.Address,
+		"detail.quote_rows":       p.Detail.QuoteRows,
+		"detail.text":             p.Detail.Text,
+		"block.challenge":         p.Block.Challenge,
+	} {
+		for _, rule := range rules {
+			selector, _ := splitRule(rule)
+			if _, err := dom.Compile(selector); err != nil {
+				return fmt.Errorf("%s: %w", field, err)
+			}
+		}
+	}
+
+	p.patterns = make(map[string][]*regexp.Regexp)
+	for _, field := range p.Detail.patternFields() {
+		patterns, err := compilePatterns("detail."+field.name, field.rules)
+		if err != nil {
+			return err
+		}
+		p.patterns[field.name] = patterns
+	}
+
+	p.challengeText = make([]*regexp.Regexp, 0, len(p.Block.ChallengeText))
+	for _, pattern := range p.Block.ChallengeText {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("block.challenge_text: %w", err)
+		}
+		p.challengeText = append(p.challengeText, re)
+	}
+	return nil
+}
+
+func compilePatterns(field string, patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field, err)
+		}
+		if re.NumSubexp() < 1 {
+			return nil, fmt.Errorf("%s: pattern %q needs a capture group for the value", field, pattern)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// splitRule separates a rule into its CSS selector and optional attribute name
+func splitRule(rule string) (selector, attr string) {
+	at := strings.LastIndexByte(rule, '@')
+	if at < 0 || at < strings.LastIndexByte(rule, ']') {
+		return rule, ""
+	}
+	return strings.TrimSpace(rule[:at]), strings.TrimSpace(rule[at+1:])
+}
+
+// anyOf joins fallback selectors into one selector list matching any of them
+func anyOf(selectors []string) string {
+	return strings.Join(selectors, ", ")
+}
+
+// firstValue returns the value of the first rule that yields a non-empty string
+func firstValue(root *dom.Node, rules []string) string {
+	for _, rule := range rules {
+		selector, attr := splitRule(rule)
+		el := root.FindFirst(selector)
+		if el == nil {
+			continue
+		}
+
+		value := ""
+		if attr != "" {
+			value = el.AttrOr(attr, "")
+		} else {
+			value = el.Text()
+		}
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// firstMatchAll returns the elements of the first selector that matches anything
+func firstMatchAll(root *dom.Node, selectors []string) []*dom.Node {
+	for _, selector := range selectors {
+		if found := root.Find(selector); len(found) > 0 {
+			return found
+		}
+	}
+	return nil
+}
+
+// firstPatternMatch returns the submatches of the first pattern that matches any text
+func firstPatternMatch(texts []string, patterns []*regexp.Regexp) []string {
+	for _, pattern := range patterns {
+		if match := findMatch(texts, pattern); match != nil {
+			return match
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package airbnb
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/config"
+)
+
+// DateRange is a stay from check-in to check-out
+type DateRange struct {
+	CheckIn  time.Time
+	CheckOut time.Time
+}
+
+// Nights returns the length of the stay
+func (r DateRange) Nights() int {
+	return int(r.CheckOut.Sub(r.CheckIn).Hours() / 24)
+}
+
+func (r DateRange) String() string {
+	return fmt.Sprintf("%s +%dn", r.CheckIn.Format(dateLayout), r.Nights())
+}
+
+// SweepDates lists the stays a sweep covers: every allowed check-in day within
+// the horizon, combined with every stay length, ordered by check-in
+func SweepDates(sweep config.SweepConfig, today time.Time) ([]DateRange, error) {
+	start := sweep.Start
+	if start == "" {
+		start = "+1"
+	}
+	first, err := parseSearchDate(start, today)
+	if err != nil {
+		return nil, fmt.Errorf("sweep start: %w", err)
+	}
+
+	weeks := sweep.Weeks
+	if weeks <= 0 {
+		weeks = 12
+	}
+
+	nights := sweep.Nights
+	if len(nights) == 0 {
+		nights = []int{1}
+	}
+	for _, n := range nights {
+		if n <= 0 {
+			return nil, fmt.Errorf("sweep nights must be positive, got %d", n)
+		}
+	}
+
+	weekdays := make(map[time.Weekday]bool)
+	for _, name := range sweep.Weekdays {
+		day, ok := parseWeekday(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown sweep weekday %q", name)
+		}
+		weekdays[day] = true
+	}
+
+	ranges := []DateRange{}
+	for day := 0; day < weeks*7; day++ {
+		checkIn := first.AddDate(0, 0, day)
+		if len(weekdays) > 0 && !weekdays[checkIn.Weekday()] {
+			continue
+		}
+		for _, n := range nights {
+			ranges = append(ranges, DateRange{CheckIn: checkIn, CheckOut: checkIn.AddDate(0, 0, n)})
+		}
+	}
+	return ranges, nil
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(strings.TrimSpace(name), day.String()) {
+			return day, true
+		}
+	}
+	return time.Sunday, false
+}
+
+// SweepLocations expands each location into one search per stay, replacing any
+// dates the location's search already had
+func SweepLocations(cards []LocationCard, ranges []DateRange) ([]LocationCard, error) {
+	swept := make([]LocationCard, 0, len(cards)*len(ranges))
+	for _, card := range cards {
+		for _, stay := range ranges {
+			query := SearchQuery{CheckIn: stay.CheckIn, CheckOut: stay.CheckOut}
+			searchURL, err := query.Apply(card.URL)
+			if err != nil {
+				return nil, err
+			}
+			swept = append(swept, LocationCard{
+				Name: fmt.Sprintf("%s [%s]", card.Name, stay),
+				URL:  searchURL,
+			})
+		}
+	}
+	return swept, nil
+}
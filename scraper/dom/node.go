@@ -0,0 +1,135 @@
+package dom
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Node is an element or document in a parsed HTML tree
+type Node struct {
+	node *html.Node
+}
+
+// Parse parses an HTML document the way a browser does, so unclosed and
+// misnested tags end up where the browser would put them
+func Parse(src string) *Node {
+	root, err := html.Parse(strings.NewReader(src))
+	if err != nil {
+		// Only read errors fail, and a string reader has none
+		return &Node{node: &html.Node{Type: html.DocumentNode}}
+	}
+	return &Node{node: root}
+}
+
+// blockTags are separated by whitespace when collecting text, similar to innerText
+var blockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true,
+	"dd": true, "div": true, "dl": true, "dt": true, "fieldset": true, "figcaption": true,
+	"figure": true, "footer": true, "form": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "header": true, "hr": true, "li": true,
+	"main": true, "nav": true, "ol": true, "p": true, "pre": true, "section": true,
+	"table": true, "td": true, "th": true, "tr": true, "ul": true,
+}
+
+// Attrs returns the element's attributes in source order
+func (n *Node) Attrs() []html.Attribute {
+	return n.node.Attr
+}
+
+// Attr returns the value of the named attribute
+func (n *Node) Attr(name string) (string, bool) {
+	for _, a := range n.node.Attr {
+		if a.Namespace == "" && a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// AttrOr returns the value of the named attribute or def if it is missing
+func (n *Node) AttrOr(name, def string) string {
+	if v, ok := n.Attr(name); ok {
+		return v
+	}
+	return def
+}
+
+// Text returns the visible text below the node with whitespace collapsed
+func (n *Node) Text() string {
+	var sb strings.Builder
+	collectText(n.node, &sb)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+func collectText(n *html.Node, sb *strings.Builder) {
+	switch n.Type {
+	case html.TextNode:
+		sb.WriteString(n.Data)
+		return
+	case html.ElementNode:
+		if n.Data == "script" || n.Data == "style" || n.Data == "template" || n.Data == "noscript" {
+			return
+		}
+	case html.CommentNode, html.DoctypeNode:
+		return
+	}
+
+	block := n.Type == html.ElementNode && blockTags[n.Data]
+	if block {
+		sb.WriteByte(' ')
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		collectText(c, sb)
+	}
+	if block {
+		sb.WriteByte(' ')
+	}
+}
+
+// RawText returns the unparsed contents of a script or style element
+func (n *Node) RawText() string {
+	var sb strings.Builder
+	for c := n.node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+	}
+	return sb.String()
+}
+
+// Elements returns all element descendants of the node in document order
+func (n *Node) Elements() []*Node {
+	var out []*Node
+	var walk func(*html.Node)
+	walk = func(parent *html.Node) {
+		for c := parent.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			out = append(out, &Node{node: c})
+			walk(c)
+		}
+	}
+	walk(n.node)
+	return out
+}
+
+// Find returns all descendants matching the CSS selector in document order.
+// An invalid selector matches nothing; use Compile to validate selectors up front.
+func (n *Node) Find(selector string) []*Node {
+	sel, err := cachedCompile(selector)
+	if err != nil {
+		return nil
+	}
+	return sel.MatchAll(n)
+}
+
+// FindFirst returns the first descendant matching the CSS selector or nil
+func (n *Node) FindFirst(selector string) *Node {
+	sel, err := cachedCompile(selector)
+	if err != nil {
+		return nil
+	}
+	return sel.MatchFirst(n)
+}
@@ -0,0 +1,68 @@
+package dom
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/cascadia"
+)
+
+// Selector is a compiled CSS selector list, matched by cascadia
+type Selector struct {
+	source string
+	group  cascadia.SelectorGroup
+}
+
+var selectorCache sync.Map
+
+func cachedCompile(source string) (*Selector, error) {
+	if sel, ok := selectorCache.Load(source); ok {
+		return sel.(*Selector), nil
+	}
+	sel, err := Compile(source)
+	if err != nil {
+		return nil, err
+	}
+	selectorCache.Store(source, sel)
+	return sel, nil
+}
+
+// Compile parses a CSS selector list
+func Compile(source string) (*Selector, error) {
+	if strings.TrimSpace(source) == "" {
+		return nil, fmt.Errorf("empty selector")
+	}
+	group, err := cascadia.ParseGroup(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %w", source, err)
+	}
+	return &Selector{source: source, group: group}, nil
+}
+
+// String returns the selector source
+func (s *Selector) String() string {
+	return s.source
+}
+
+// MatchAll returns the descendants of root that match, in document order
+func (s *Selector) MatchAll(root *Node) []*Node {
+	var out []*Node
+	for _, n := range cascadia.QueryAll(root.node, s.group) {
+		out = append(out, &Node{node: n})
+	}
+	return out
+}
+
+// MatchFirst returns the first descendant of root that matches or nil
+func (s *Selector) MatchFirst(root *Node) *Node {
+	if n := cascadia.Query(root.node, s.group); n != nil {
+		return &Node{node: n}
+	}
+	return nil
+}
+
+// Matches reports whether the element matches
+func (s *Selector) Matches(el *Node) bool {
+	return s.group.Match(el.node)
+}
@@ -11,13 +11,16 @@ import (
 
 // AnalyticsService handles analytics and insights
 type AnalyticsService struct {
-	db     *storage.DB
-	logger *utils.Logger
+	db       *storage.DB
+	logger   *utils.Logger
+	currency *utils.CurrencyConverter
 }
 
 // Analytics holds all calculated statistics
 type Analytics struct {
 	TotalListings       int
+	Currency            string // currency of the price statistics
+	Unconverted         int    // listings left out of the price statistics for lack of an exchange rate
 	AveragePrice        float64
 	MaxPrice            float64
 	MinPrice            float64
@@ -26,11 +29,12 @@ type Analytics struct {
 	TopRated            []models.Listing
 }
 
-// NewAnalyticsService creates a new analytics service
-func NewAnalyticsService(db *storage.DB, logger *utils.Logger) *AnalyticsService {
+// NewAnalyticsService creates a new analytics service reporting prices in the converter's target currency
+func NewAnalyticsService(db *storage.DB, logger *utils.Logger, currency *utils.CurrencyConverter) *AnalyticsService {
 	return &AnalyticsService{
-		db:     db,
-		logger: logger,
+		db:       db,
+		logger:   logger,
+		currency: currency,
 	}
 }
 
@@ -42,40 +46,56 @@ func (s *AnalyticsService) GetAnalytics() (*Analytics, error) {
 	}
 
 	if len(listings) == 0 {
-		return &Analytics{}, nil
+		return &Analytics{Currency: s.currency.Target}, nil
 	}
 
 	analytics := &Analytics{
 		TotalListings:       len(listings),
+		Currency:            s.currency.Target,
 		ListingsPerLocation: make(map[string]int),
 	}
 
-	// Calculate price statistics
+	// Calculate price statistics in the target currency, at the rate of the day
+	// each price was last seen
 	var totalPrice float64
-	analytics.MaxPrice = listings[0].Price
-	analytics.MinPrice = listings[0].Price
-	analytics.MostExpensive = &listings[0]
+	priced := 0
 
 	for i := range listings {
 		listing := &listings[i]
 
+		// Location grouping
+		analytics.ListingsPerLocation[placeOf(listing)]++
+
+		price, err := s.currency.Convert(listing.Price, listing.Currency, listing.UpdatedAt)
+		if err != nil {
+			analytics.Unconverted++
+			listing.Currency = s.currency.Currency(listing.Currency)
+			continue
+		}
+		listing.Price = price
+		listing.Currency = s.currency.Target
+
 		// Price calculations
 		totalPrice += listing.Price
 
-		if listing.Price > analytics.MaxPrice {
+		if priced == 0 || listing.Price > analytics.MaxPrice {
 			analytics.MaxPrice = listing.Price
 			analytics.MostExpensive = listing
 		}
 
-		if listing.Price < analytics.MinPrice {
+		if priced == 0 || listing.Price < analytics.MinPrice {
 			analytics.MinPrice = listing.Price
 		}
-
-		// Location grouping
-		analytics.ListingsPerLocation[listing.Location]++
+		priced++
 	}
 
-	analytics.AveragePrice = totalPrice / float64(len(listings))
+	if priced > 0 {
+		analytics.AveragePrice = totalPrice / float64(priced)
+	}
+	if analytics.Unconverted > 0 {
+		s.logger.Warning("%d listings have no exchange rate to %s and are left out of the price statistics",
+			analytics.Unconverted, s.currency.Target)
+	}
 
 	// Get top 5 rated properties
 	analytics.TopRated = s.getTopRated(listings, 5)
@@ -83,6 +103,21 @@ func (s *AnalyticsService) GetAnalytics() (*Analytics, error) {
 	return analytics, nil
 }
 
+// placeOf names where a listing is for grouping: its neighborhood, city and
+// country when the detail page gave them, otherwise the card's location text
+func placeOf(listing *models.Listing) string {
+	if listing.City == "" {
+		return listing.Location
+	}
+	parts := []string{}
+	for _, part := range []string{listing.Neighborhood, listing.City, listing.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
 // getTopRated returns top N highest rated listings
 func (s *AnalyticsService) getTopRated(listings []models.Listing, n int) []models.Listing {
 	// Sort by rating (descending)
@@ -117,15 +152,19 @@ func (s *AnalyticsService) PrintAnalytics(analytics *Analytics) {
 
 	// Price statistics
 	s.logger.Info("   PRICE STATISTICS:")
-	s.logger.Info("   Average Price:        $%.2f", analytics.AveragePrice)
-	s.logger.Info("   Maximum Price:        $%.2f", analytics.MaxPrice)
-	s.logger.Info("   Minimum Price:        $%.2f\n", analytics.MinPrice)
+	s.logger.Info("   Average Price:        %s", utils.FormatMoney(analytics.AveragePrice, analytics.Currency))
+	s.logger.Info("   Maximum Price:        %s", utils.FormatMoney(analytics.MaxPrice, analytics.Currency))
+	s.logger.Info("   Minimum Price:        %s", utils.FormatMoney(analytics.MinPrice, analytics.Currency))
+	if analytics.Unconverted > 0 {
+		s.logger.Info("   Without exchange rate: %d listings", analytics.Unconverted)
+	}
+	s.logger.Info("")
 
 	// Most expensive property
 	if analytics.MostExpensive != nil {
 		s.logger.Info("   MOST EXPENSIVE PROPERTY:")
 		s.logger.Info("   Title:                %s", analytics.MostExpensive.Title)
-		s.logger.Info("   Price:                $%.2f per night", analytics.MostExpensive.Price)
+		s.logger.Info("   Price:                %s per night", utils.FormatMoney(analytics.MostExpensive.Price, analytics.MostExpensive.Currency))
 		s.logger.Info("   Location:             %s", analytics.MostExpensive.Location)
 		s.logger.Info("   Rating:               %.2f ⭐", analytics.MostExpensive.Rating)
 		s.logger.Info("   Bedrooms: %d | Bathrooms: %d | Guests: %d\n",
@@ -145,8 +184,8 @@ func (s *AnalyticsService) PrintAnalytics(analytics *Analytics) {
 	s.logger.Info("⭐ TOP 5 HIGHEST RATED PROPERTIES:")
 	for i, listing := range analytics.TopRated {
 		s.logger.Info("\n   %d. %s", i+1, listing.Title)
-		s.logger.Info("      Rating: %.2f ⭐ | Price: $%.2f | Location: %s",
-			listing.Rating, listing.Price, listing.Location)
+		s.logger.Info("      Rating: %.2f ⭐ | Price: %s | Location: %s",
+			listing.Rating, utils.FormatMoney(listing.Price, listing.Currency), listing.Location)
 	}
 
 	// Footer
@@ -159,7 +198,7 @@ func (s *AnalyticsService) PrintAveragePrice() error {
 	if err != nil {
 		return err
 	}
-	s.logger.Info("\nAverage Price: $%.2f\n", analytics.AveragePrice)
+	s.logger.Info("\nAverage Price: %s\n", utils.FormatMoney(analytics.AveragePrice, analytics.Currency))
 	return nil
 }
 
@@ -173,7 +212,7 @@ func (s *AnalyticsService) PrintMaxPrice() error {
 	s.logger.Info("\n MOST EXPENSIVE PROPERTY:")
 	if analytics.MostExpensive != nil {
 		s.logger.Info("   Title:      %s", analytics.MostExpensive.Title)
-		s.logger.Info("   Price:      $%.2f per night", analytics.MostExpensive.Price)
+		s.logger.Info("   Price:      %s per night", utils.FormatMoney(analytics.MostExpensive.Price, analytics.MostExpensive.Currency))
 		s.logger.Info("   Location:   %s", analytics.MostExpensive.Location)
 		s.logger.Info("   Rating:     %.2f ⭐", analytics.MostExpensive.Rating)
 		s.logger.Info("   URL:        %s\n", analytics.MostExpensive.URL)
@@ -192,7 +231,7 @@ func (s *AnalyticsService) PrintTopRated() error {
 	for i, listing := range analytics.TopRated {
 		s.logger.Info("\n   %d. %s", i+1, listing.Title)
 		s.logger.Info("      Rating:    %.2f ⭐", listing.Rating)
-		s.logger.Info("      Price:     $%.2f per night", listing.Price)
+		s.logger.Info("      Price:     %s per night", utils.FormatMoney(listing.Price, listing.Currency))
 		s.logger.Info("      Location:  %s", listing.Location)
 		s.logger.Info("      Bedrooms: %d | Bathrooms: %d | Guests: %d",
 			listing.Bedrooms, listing.Bathrooms, listing.Guests)
@@ -215,3 +254,47 @@ func (s *AnalyticsService) PrintByLocation() error {
 	s.logger.Info("")
 	return nil
 }
+
+// PrintPriceCurve prints the prices a listing showed for each swept stay, by check-in date
+func (s *AnalyticsService) PrintPriceCurve(url string) error {
+	prices, err := s.db.GetListingPrices(utils.NormalizeURL(url))
+	if err != nil {
+		return err
+	}
+	if len(prices) == 0 {
+		s.logger.Warning("No stay prices recorded for %s (run a date sweep first)", url)
+		return nil
+	}
+
+	s.logger.Info("\n PRICE CURVE: %s", prices[0].URL)
+	for _, p := range prices {
+		currency := s.currency.Currency(p.Currency)
+		s.logger.Info("   %s  %s  %2d nights  %s %8.2f total  %7.2f per night",
+			p.CheckIn.Format("2006-01-02"), p.CheckIn.Weekday().String()[:3], p.Nights, currency, p.NightlyPrice*float64(p.Nights), p.NightlyPrice)
+	}
+	s.logger.Info("")
+	return nil
+}
+
+// PrintQuotes prints the price breakdowns a listing's detail page quoted, by check-in date
+func (s *AnalyticsService) PrintQuotes(url string) error {
+	quotes, err := s.db.GetPriceQuotes(utils.NormalizeURL(url))
+	if err != nil {
+		return err
+	}
+	if len(quotes) == 0 {
+		s.logger.Warning("No price quotes recorded for %s (enable scraper.quote first)", url)
+		return nil
+	}
+
+	s.logger.Info("\n PRICE QUOTES: %s", quotes[0].URL)
+	for _, q := range quotes {
+		s.logger.Info("   %s  %2d nights  %d guests  (observed %s)",
+			q.CheckIn.Format("2006-01-02"), q.Nights, q.Guests, q.ObservedAt.Format("2006-01-02"))
+		s.logger.Info("      %2d × %.2f = %.2f, %.2f after discounts (%s)",
+			q.Nights, q.NightlyRate, q.OriginalPrice, q.DiscountedPrice, s.currency.Currency(q.Currency))
+		s.logger.Info("      cleaning %.2f  service %.2f  taxes %.2f  total %.2f", q.CleaningFee, q.ServiceFee, q.Taxes, q.Total)
+	}
+	s.logger.Info("")
+	return nil
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"github.com/farhanasfar/airbnb-market-scraping-system/models"
+	"github.com/farhanasfar/airbnb-market-scraping-system/storage"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// CalendarService stores availability calendar snapshots
+type CalendarService struct {
+	db     *storage.DB
+	logger *utils.Logger
+}
+
+// NewCalendarService creates a new calendar service
+func NewCalendarService(db *storage.DB, logger *utils.Logger) *CalendarService {
+	return &CalendarService{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveSnapshot stores a listing's calendar as seen today
+func (s *CalendarService) SaveSnapshot(url string, days []models.CalendarDay) error {
+	if err := s.db.SaveCalendarSnapshot(days); err != nil {
+		return err
+	}
+
+	open := 0
+	for _, day := range days {
+		if day.Available {
+			open++
+		}
+	}
+	s.logger.Info("✓ Calendar saved: %s (%d of %d nights open)", url, open, len(days))
+	return nil
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/models"
+	"github.com/farhanasfar/airbnb-market-scraping-system/storage"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// CheckpointService persists the progress of a scraping run so an
+// interrupted or crashed run can be resumed with --resume <run-id>
+type CheckpointService struct {
+	db     *storage.DB
+	logger *utils.Logger
+}
+
+// NewCheckpointService creates a new checkpoint service
+func NewCheckpointService(db *storage.DB, logger *utils.Logger) *CheckpointService {
+	return &CheckpointService{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// StartRun creates a new run identified by its start time, to the millisecond,
+// and a random suffix so runs started together do not collide
+func (c *CheckpointService) StartRun() (*models.CrawlRun, error) {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("failed to generate run ID: %w", err)
+	}
+	id := time.Now().Format("20060102-150405.000") + "-" + hex.EncodeToString(suffix)
+	return c.db.CreateCrawlRun(id)
+}
+
+// ResumeRun loads an earlier run. Completed runs cannot be resumed.
+func (c *CheckpointService) ResumeRun(id string) (*models.CrawlRun, error) {
+	run, err := c.db.GetCrawlRun(id)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status == models.CrawlCompleted {
+		return nil, fmt.Errorf("run %s already completed", id)
+	}
+
+	c.logger.Info("Resuming run %s started %s", run.ID, run.CreatedAt.Format(time.RFC1123))
+	return run, nil
+}
+
+// SaveLocations records the locations a run will scrape, numbering them in order
+func (c *CheckpointService) SaveLocations(runID string, locations []models.CrawlLocation) ([]models.CrawlLocation, error) {
+	for i := range locations {
+		locations[i].Position = i + 1
+	}
+	if err := c.db.InsertCrawlLocations(runID, locations); err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+// Locations returns a run's locations with their progress
+func (c *CheckpointService) Locations(runID string) ([]models.CrawlLocation, error) {
+	return c.db.GetCrawlLocations(runID)
+}
+
+// SavePage checkpoints a scraped results page. location carries the progress after
+// the page: PagesDone is the page just scraped and NextURL the page to continue
+// from, empty when the location has no more pages.
+func (c *CheckpointService) SavePage(runID string, location models.CrawlLocation, listings []models.RawListing) error {
+	if err := c.db.SaveCrawlPage(runID, location, listings); err != nil {
+		return err
+	}
+	c.logger.Info("Checkpointed page %d of %s (%d listings)", location.PagesDone, location.Name, len(listings))
+	return nil
+}
+
+// FinishLocation marks a location as fully scraped
+func (c *CheckpointService) FinishLocation(runID string, location models.CrawlLocation) error {
+	return c.db.FinishCrawlLocation(runID, location.Position)
+}
+
+// PendingListings returns the listings a run scraped whose detail pages are not merged yet
+func (c *CheckpointService) PendingListings(runID string) ([]models.RawListing, error) {
+	return c.db.GetPendingCrawlListings(runID)
+}
+
+// SaveDetail checkpoints a listing once its detail page is merged in
+func (c *CheckpointService) SaveDetail(runID string, listing models.RawListing) error {
+	return c.db.SaveCrawlDetail(runID, listing)
+}
+
+// CompleteRun marks a run as finished so it is not resumed again. A run with
+// unfinished locations or pending detail pages is marked partial instead and
+// stays resumable; the returned bool reports whether the run was completed.
+func (c *CheckpointService) CompleteRun(runID string) (bool, error) {
+	locations, err := c.db.GetCrawlLocations(runID)
+	if err != nil {
+		return false, err
+	}
+	unfinished := 0
+	for _, location := range locations {
+		if !location.Done {
+			unfinished++
+		}
+	}
+	pending, err := c.db.GetPendingCrawlListings(runID)
+	if err != nil {
+		return false, err
+	}
+
+	if unfinished > 0 || len(pending) > 0 {
+		c.logger.Warning("Run %s left %d locations and %d detail pages unfinished", runID, unfinished, len(pending))
+		return false, c.db.UpdateCrawlRunStatus(runID, models.CrawlPartial)
+	}
+	return true, c.db.UpdateCrawlRunStatus(runID, models.CrawlCompleted)
+}
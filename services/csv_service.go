@@ -3,7 +3,9 @@ package services
 import (
 	"encoding/csv"
 	"fmt"
+	"net/url"
 	"os"
+	"strings"
 
 	"github.com/farhanasfar/airbnb-market-scraping-system/models"
 	"github.com/farhanasfar/airbnb-market-scraping-system/storage"
@@ -12,15 +14,18 @@ import (
 
 // CSVService handles CSV export operations
 type CSVService struct {
-	db     *storage.DB
-	logger *utils.Logger
+	db       *storage.DB
+	logger   *utils.Logger
+	currency *utils.CurrencyConverter
 }
 
-// NewCSVService creates a new CSV service
-func NewCSVService(db *storage.DB, logger *utils.Logger) *CSVService {
+// NewCSVService creates a new CSV service. Exports carry each price as found and
+// converted to the converter's target currency.
+func NewCSVService(db *storage.DB, logger *utils.Logger, currency *utils.CurrencyConverter) *CSVService {
 	return &CSVService{
-		db:     db,
-		logger: logger,
+		db:       db,
+		logger:   logger,
+		currency: currency,
 	}
 }
 
@@ -54,12 +59,35 @@ func (s *CSVService) ExportToCSV(filename string) error {
 		"ID",
 		"Title",
 		"Price",
+		"Currency",
+		"Price " + s.currency.Target,
 		"Location",
+		"Neighborhood",
+		"City",
+		"Region",
+		"Country",
+		"Latitude",
+		"Longitude",
 		"Rating",
 		"Bedrooms",
 		"Bathrooms",
 		"Guests",
+		"Property Type",
+		"Room Type",
+		"Beds",
+		"Amenities",
+		"Host",
+		"Host ID",
+		"Superhost",
+		"Host Since",
+		"Response Rate",
+		"Check-in",
+		"Check-out",
+		"Min Nights",
+		"Cancellation",
+		"Instant Book",
 		"URL",
+		"Search",
 		"Created At",
 	}
 
@@ -73,12 +101,35 @@ func (s *CSVService) ExportToCSV(filename string) error {
 			fmt.Sprintf("%d", listing.ID),
 			listing.Title,
 			fmt.Sprintf("%.2f", listing.Price),
+			s.currency.Currency(listing.Currency),
+			s.targetPrice(listing),
 			listing.Location,
+			listing.Neighborhood,
+			listing.City,
+			listing.Region,
+			listing.Country,
+			fmt.Sprintf("%.6f", listing.Latitude),
+			fmt.Sprintf("%.6f", listing.Longitude),
 			fmt.Sprintf("%.2f", listing.Rating),
 			fmt.Sprintf("%d", listing.Bedrooms),
 			fmt.Sprintf("%d", listing.Bathrooms),
 			fmt.Sprintf("%d", listing.Guests),
+			listing.PropertyType,
+			listing.RoomType,
+			fmt.Sprintf("%d", listing.Beds),
+			strings.Join(listing.Amenities, "; "),
+			listing.HostName,
+			listing.HostID,
+			fmt.Sprintf("%t", listing.Superhost),
+			fmt.Sprintf("%d", listing.HostJoinYear),
+			fmt.Sprintf("%d", listing.HostResponseRate),
+			listing.CheckInTime,
+			listing.CheckOutTime,
+			fmt.Sprintf("%d", listing.MinNights),
+			listing.CancellationPolicy,
+			fmt.Sprintf("%t", listing.InstantBook),
 			listing.URL,
+			encodeSearch(listing.SearchParams),
 			listing.CreatedAt.Format("2006-01-02 15:04:05"),
 		}
 
@@ -114,6 +165,8 @@ func (s *CSVService) ExportListingsToCSV(listings []models.Listing, filename str
 	header := []string{
 		"Title",
 		"Price",
+		"Currency",
+		"Price " + s.currency.Target,
 		"Location",
 		"Rating",
 		"Bedrooms",
@@ -131,6 +184,8 @@ func (s *CSVService) ExportListingsToCSV(listings []models.Listing, filename str
 		row := []string{
 			listing.Title,
 			fmt.Sprintf("%.2f", listing.Price),
+			s.currency.Currency(listing.Currency),
+			s.targetPrice(listing),
 			listing.Location,
 			fmt.Sprintf("%.2f", listing.Rating),
 			fmt.Sprintf("%d", listing.Bedrooms),
@@ -147,3 +202,22 @@ func (s *CSVService) ExportListingsToCSV(listings []models.Listing, filename str
 	s.logger.Success("Exported %d listings to %s", len(listings), filename)
 	return nil
 }
+
+// targetPrice formats a listing's price in the target currency, or "" when there
+// is no exchange rate for it
+func (s *CSVService) targetPrice(listing models.Listing) string {
+	price, err := s.currency.Convert(listing.Price, listing.Currency, listing.UpdatedAt)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%.2f", price)
+}
+
+// encodeSearch writes search parameters as a query string, e.g. "adults=2&query=Lisbon"
+func encodeSearch(params map[string]string) string {
+	values := url.Values{}
+	for key, value := range params {
+		values.Set(key, value)
+	}
+	return values.Encode()
+}
@@ -2,6 +2,9 @@ package services
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/farhanasfar/airbnb-market-scraping-system/models"
 	"github.com/farhanasfar/airbnb-market-scraping-system/storage"
@@ -33,13 +36,15 @@ func (scrape *ListingService) NormalizeAndSave(rawListings []models.RawListing)
 
 	for _, raw := range rawListings {
 		// Normalize the data
-		listing := scrape.normalize(raw)
+		numbers := parseNumbers(raw)
+		listing := scrape.normalize(raw, numbers)
 
 		// Skip invalid listings
 		if listing.Title == "" || listing.URL == "" {
 			scrape.logger.Warning("Skipping invalid listing (no title or URL)")
 			continue
 		}
+		scrape.warnUnreadable(raw, numbers)
 
 		// Save to database (ON CONFLICT handles duplicates)
 		err := scrape.db.InsertListing(&listing)
@@ -50,7 +55,7 @@ func (scrape *ListingService) NormalizeAndSave(rawListings []models.RawListing)
 		}
 
 		successCount++
-		scrape.logger.Info("✓ Saved: %s ($%.2f)", listing.Title, listing.Price)
+		scrape.logger.Info("✓ Saved: %s (%s)", listing.Title, utils.FormatMoney(listing.Price, listing.Currency))
 	}
 
 	scrape.logger.Success("Saved %d listings to database", successCount)
@@ -62,17 +67,194 @@ func (scrape *ListingService) NormalizeAndSave(rawListings []models.RawListing)
 	return successCount, nil
 }
 
-// normalize converts RawListing to normalized Listing
-func (s *ListingService) normalize(raw models.RawListing) models.Listing {
+// SaveDetails patches the detail page fields of an already saved listing
+func (s *ListingService) SaveDetails(raw models.RawListing) error {
+	listing := s.normalize(raw, parseNumbers(raw))
+	if err := s.db.UpdateListingDetails(&listing); err != nil {
+		return err
+	}
+
+	s.logger.Info("✓ Details saved: %s (%d bedrooms, %d baths, %d guests, %d amenities)",
+		listing.Title, listing.Bedrooms, listing.Bathrooms, listing.Guests, len(listing.Amenities))
+	return nil
+}
+
+// SavePrices records the price each listing showed for the stay its search asked for,
+// and its price breakdown when the search result carried one.
+// Listings found without check-in and check-out dates are skipped.
+func (s *ListingService) SavePrices(rawListings []models.RawListing) int {
+	saved, quoted := 0, 0
+	for _, raw := range rawListings {
+		price, ok := s.stayPrice(raw)
+		if !ok {
+			continue
+		}
+		if err := s.db.UpsertListingPrice(&price); err != nil {
+			s.logger.Error("Failed to save price of '%s': %v", raw.Title, err)
+			continue
+		}
+		saved++
+
+		if raw.Quote == nil {
+			continue
+		}
+		quote := searchQuote(raw, price)
+		if err := s.db.UpsertPriceQuote(&quote); err != nil {
+			s.logger.Error("Failed to save price breakdown of '%s': %v", raw.Title, err)
+			continue
+		}
+		quoted++
+	}
+
+	if saved > 0 {
+		s.logger.Info("✓ Recorded %d stay prices, %d with a price breakdown", saved, quoted)
+	}
+	return saved
+}
+
+// searchQuote fills in the stay of a breakdown read from a search result.
+// Searches without adults are priced by Airbnb for one guest.
+func searchQuote(raw models.RawListing, price models.ListingPrice) models.PriceQuote {
+	quote := *raw.Quote
+	quote.URL = price.URL
+	quote.CheckIn = price.CheckIn
+	quote.CheckOut = price.CheckOut
+	quote.Guests, _ = strconv.Atoi(raw.SearchParams["adults"])
+	if quote.Guests == 0 {
+		quote.Guests = 1
+	}
+	if quote.Nights == 0 {
+		quote.Nights = price.Nights
+	}
+	if quote.Currency == "" {
+		quote.Currency = price.Currency
+	}
+	return quote
+}
+
+// SaveQuote records the price breakdown a listing's detail page quoted
+func (s *ListingService) SaveQuote(quote *models.PriceQuote) error {
+	if err := s.db.UpsertPriceQuote(quote); err != nil {
+		return err
+	}
+
+	s.logger.Info("✓ Quote saved: %s (%d nights, %.2f total, %.2f cleaning, %.2f service, %.2f taxes %s)",
+		quote.URL, quote.Nights, quote.Total, quote.CleaningFee, quote.ServiceFee, quote.Taxes, quote.Currency)
+	return nil
+}
+
+// stayPrice reads the stay a listing was priced for from its search parameters
+func (s *ListingService) stayPrice(raw models.RawListing) (models.ListingPrice, bool) {
+	checkIn, checkOut, ok := stayDates(raw)
+	if !ok {
+		return models.ListingPrice{}, false
+	}
+	price := utils.ParseLocale(raw.Locale).ParsePrice(raw.Price).Value
+	if price == 0 {
+		return models.ListingPrice{}, false
+	}
+
+	return models.ListingPrice{
+		URL:          utils.NormalizeURL(raw.URL),
+		CheckIn:      checkIn,
+		CheckOut:     checkOut,
+		Nights:       stayNights(checkIn, checkOut),
+		Price:        price,
+		NightlyPrice: nightlyPrice(raw, price),
+		Currency:     priceCurrency(raw),
+	}, true
+}
+
+// stayDates returns the check-in and check-out dates of the listing's search, if it had any
+func stayDates(raw models.RawListing) (time.Time, time.Time, bool) {
+	checkIn, err := time.Parse("2006-01-02", raw.SearchParams["checkin"])
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	checkOut, err := time.Parse("2006-01-02", raw.SearchParams["checkout"])
+	if err != nil || !checkOut.After(checkIn) {
+		return time.Time{}, time.Time{}, false
+	}
+	return checkIn, checkOut, true
+}
+
+// stayNights returns how many nights a stay lasts
+func stayNights(checkIn, checkOut time.Time) int {
+	return int(checkOut.Sub(checkIn).Hours() / 24)
+}
+
+// nightlyPrice converts a card price to a price per night. Dated searches may show
+// the whole stay, e.g. "$600 total", which is divided by the nights searched;
+// a total without search dates is left as it is.
+func nightlyPrice(raw models.RawListing, price float64) float64 {
+	if !utils.IsStayTotal(raw.Price) {
+		return price
+	}
+	checkIn, checkOut, ok := stayDates(raw)
+	if !ok {
+		return price
+	}
+	return price / float64(stayNights(checkIn, checkOut))
+}
+
+// priceCurrency returns the ISO code of a listing's price, preferring the code its
+// page's state named and reading a shared symbol such as "$" by the page's region
+func priceCurrency(raw models.RawListing) string {
+	return utils.PriceCurrency(raw.Price, raw.Currency, utils.ParseLocale(raw.Locale), raw.URL)
+}
+
+// listingNumbers are a listing's price and rating read in its page's locale
+type listingNumbers struct {
+	locale utils.Locale
+	price  utils.ParsedNumber
+	rating utils.ParsedNumber
+}
+
+// parseNumbers reads a listing's price and rating once, for normalize and warnUnreadable
+func parseNumbers(raw models.RawListing) listingNumbers {
+	locale := utils.ParseLocale(raw.Locale)
+	return listingNumbers{
+		locale: locale,
+		price:  locale.ParsePrice(raw.Price),   // "1.234,56 €" -> 1234.56
+		rating: locale.ParseRating(raw.Rating), // "4,95 (123)" -> 4.95
+	}
+}
+
+// warnUnreadable logs a listing's price or rating when it could not be read, or
+// could only be read by guessing from the page locale
+func (s *ListingService) warnUnreadable(raw models.RawListing, numbers listingNumbers) {
+	if price := numbers.price; price.Confidence != utils.Certain {
+		s.logger.Warning("Price of '%s' %s (%s): %s", raw.Title, price.Confidence, numbers.locale.Tag, price.Reason)
+	}
+	// Listings without reviews show "New" instead of a rating, which is not a parsing problem
+	rating := numbers.rating
+	if strings.ContainsAny(utils.NormalizeDigits(raw.Rating), "0123456789") && rating.Confidence != utils.Certain {
+		s.logger.Warning("Rating of '%s' %s (%s): %s", raw.Title, rating.Confidence, numbers.locale.Tag, rating.Reason)
+	}
+}
+
+// normalize converts RawListing to normalized Listing using its already parsed numbers
+func (s *ListingService) normalize(raw models.RawListing, numbers listingNumbers) models.Listing {
 	return models.Listing{
 		Title:     raw.Title,
-		Price:     utils.NormalizePrice(raw.Price), // "$120" -> 120.0
+		Price:     nightlyPrice(raw, numbers.price.Value), // "$600 total" for 3 nights -> 200
+		Currency:  priceCurrency(raw),                     // "$120" on airbnb.ca -> "CAD"
 		Location:  raw.Location,
-		Rating:    utils.NormalizeRating(raw.Rating), // "4.95 (123)" -> 4.95
-		URL:       utils.NormalizeURL(raw.URL),       //removing query params as it keeps changing and duplicate data gets added.
+		Rating:    numbers.rating.Value,
+		URL:       utils.NormalizeURL(raw.URL), //removing query params as it keeps changing and duplicate data gets added.
 		Bedrooms:  raw.Bedrooms,
 		Bathrooms: raw.Bathrooms,
 		Guests:    raw.Guests,
+
+		Latitude:     raw.Latitude,
+		Longitude:    raw.Longitude,
+		Neighborhood: raw.Neighborhood,
+		City:         raw.City,
+		Region:       raw.Region,
+		Country:      raw.Country,
+
+		SearchParams:   raw.SearchParams,
+		ListingDetails: raw.ListingDetails,
 	}
 }
 
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/models"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// blockedRunNights is the length above which a run of nights that closed between
+// two snapshots is taken as the host blocking the calendar rather than a booking
+const blockedRunNights = 28
+
+// EstimateOccupancy infers a listing's bookings from its calendar snapshots.
+// A future night that was open in one snapshot and closed in the next was most
+// likely booked; closures longer than four weeks are counted as host blocks.
+// Nights that were never seen open tell nothing and are not tracked.
+func (s *AnalyticsService) EstimateOccupancy(url string) (*models.Occupancy, error) {
+	days, err := s.db.GetCalendarSnapshots(utils.NormalizeURL(url))
+	if err != nil {
+		return nil, err
+	}
+	occupancy := inferOccupancy(days)
+	occupancy.URL = utils.NormalizeURL(url)
+	return &occupancy, nil
+}
+
+// inferOccupancy compares each pair of consecutive snapshots in days, which are
+// ordered by snapshot date
+func inferOccupancy(days []models.CalendarDay) models.Occupancy {
+	var snapshots []map[string]bool // night (YYYY-MM-DD) -> available, one map per snapshot
+	var occupancy models.Occupancy
+	for i, day := range days {
+		if i == 0 || !day.SnapshotDate.Equal(days[i-1].SnapshotDate) {
+			snapshots = append(snapshots, make(map[string]bool))
+			if occupancy.From.IsZero() {
+				occupancy.From = day.SnapshotDate
+			}
+			occupancy.To = day.SnapshotDate
+		}
+		snapshots[len(snapshots)-1][day.Date.Format("2006-01-02")] = day.Available
+	}
+	occupancy.Snapshots = len(snapshots)
+
+	tracked := make(map[string]bool)
+	closed := make(map[string]bool)
+	for i := 1; i < len(snapshots); i++ {
+		for night, open := range snapshots[i-1] {
+			later, seen := snapshots[i][night]
+			if !open || !seen {
+				continue // closed already, or in the past by the next snapshot
+			}
+			tracked[night] = true
+			if !later {
+				closed[night] = true
+			}
+		}
+	}
+	occupancy.TrackedNights = len(tracked)
+
+	// Split the closed nights into runs of consecutive nights
+	nights := make([]time.Time, 0, len(closed))
+	for night := range closed {
+		date, _ := time.Parse("2006-01-02", night)
+		nights = append(nights, date)
+	}
+	sort.Slice(nights, func(i, j int) bool { return nights[i].Before(nights[j]) })
+	for start := 0; start < len(nights); {
+		end := start + 1
+		for end < len(nights) && nights[end].Sub(nights[end-1]) <= 24*time.Hour {
+			end++
+		}
+		if run := end - start; run > blockedRunNights {
+			occupancy.BlockedNights += run
+		} else {
+			occupancy.BookedNights += run
+		}
+		start = end
+	}
+
+	return occupancy
+}
+
+// PrintOccupancy prints the estimated occupancy of every listing with at least two
+// calendar snapshots, and the total per market (city, or location text when unknown)
+func (s *AnalyticsService) PrintOccupancy() error {
+	listings, err := s.db.GetAllListings()
+	if err != nil {
+		return err
+	}
+
+	type market struct {
+		models.Occupancy
+		listings int
+	}
+	markets := make(map[string]*market)
+	var names []string
+	s.logger.Info("\n ESTIMATED OCCUPANCY (from calendar snapshots):")
+	for i := range listings {
+		occupancy, err := s.EstimateOccupancy(listings[i].URL)
+		if err != nil {
+			return err
+		}
+		if occupancy.Snapshots < 2 {
+			continue
+		}
+
+		s.logger.Info("   %-45s %5.1f%%  %3d booked / %3d tracked nights, %d blocked (%d snapshots)",
+			occupancy.URL, occupancy.Rate()*100, occupancy.BookedNights, occupancy.TrackedNights,
+			occupancy.BlockedNights, occupancy.Snapshots)
+
+		name := listings[i].City
+		if name == "" {
+			name = listings[i].Location
+		}
+		total, ok := markets[name]
+		if !ok {
+			total = &market{Occupancy: models.Occupancy{City: name}}
+			markets[name] = total
+			names = append(names, name)
+		}
+		total.listings++
+		total.TrackedNights += occupancy.TrackedNights
+		total.BookedNights += occupancy.BookedNights
+		total.BlockedNights += occupancy.BlockedNights
+	}
+
+	if len(names) == 0 {
+		s.logger.Warning("No listing has two calendar snapshots yet (run --calendar on different days)")
+		return nil
+	}
+
+	sort.Strings(names)
+	s.logger.Info("\n OCCUPANCY BY MARKET:")
+	for _, name := range names {
+		total := markets[name]
+		s.logger.Info("   %-35s %5.1f%%  %d listings, %d booked / %d tracked nights",
+			name, total.Rate()*100, total.listings, total.BookedNights, total.TrackedNights)
+	}
+	s.logger.Info("")
+	return nil
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/models"
+	"github.com/farhanasfar/airbnb-market-scraping-system/storage"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// ReviewService stores the guest reviews of listings
+type ReviewService struct {
+	db     *storage.DB
+	logger *utils.Logger
+}
+
+// NewReviewService creates a new review service
+func NewReviewService(db *storage.DB, logger *utils.Logger) *ReviewService {
+	return &ReviewService{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Since returns the date reviews of a listing must be fetched from: the date of
+// its newest stored review, or the zero time when it has none yet.
+// Reviews from that day are fetched again in case more were posted later that day.
+func (s *ReviewService) Since(url string) (time.Time, error) {
+	latest, err := s.db.LatestReviewDate(url)
+	if err != nil || latest.IsZero() {
+		return time.Time{}, err
+	}
+	return time.Date(latest.Year(), latest.Month(), latest.Day(), 0, 0, 0, 0, latest.Location()), nil
+}
+
+// SaveReviews stores a listing's reviews and returns how many were saved
+func (s *ReviewService) SaveReviews(url string, reviews []models.Review) int {
+	saved := 0
+	for i := range reviews {
+		if err := s.db.UpsertReview(&reviews[i]); err != nil {
+			s.logger.Error("Failed to save review %s of %s: %v", reviews[i].ReviewID, url, err)
+			continue
+		}
+		saved++
+	}
+
+	s.logger.Info("✓ Saved %d reviews of %s", saved, url)
+	return saved
+}
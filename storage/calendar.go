@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/models"
+)
+
+// SaveCalendarSnapshot stores one snapshot of a listing's calendar in a single
+// transaction, replacing an earlier snapshot taken the same day
+func (db *DB) SaveCalendarSnapshot(days []models.CalendarDay) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, day := range days {
+		_, err := tx.Exec(`
+			INSERT INTO calendar_snapshots (url, snapshot_date, date, available, check_in, check_out, min_nights, max_nights)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (url, snapshot_date, date) DO UPDATE SET
+				available = EXCLUDED.available,
+				check_in = EXCLUDED.check_in,
+				check_out = EXCLUDED.check_out,
+				min_nights = EXCLUDED.min_nights,
+				max_nights = EXCLUDED.max_nights`,
+			day.URL, day.SnapshotDate, day.Date, day.Available,
+			day.CheckIn, day.CheckOut, day.MinNights, day.MaxNights,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert calendar day: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save calendar snapshot: %w", err)
+	}
+	return nil
+}
+
+// GetCalendarSnapshots returns every stored calendar night of a listing,
+// ordered by snapshot date and then night
+func (db *DB) GetCalendarSnapshots(url string) ([]models.CalendarDay, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, url, snapshot_date, date, available, check_in, check_out, min_nights, max_nights
+		FROM calendar_snapshots
+		WHERE url = $1
+		ORDER BY snapshot_date, date`,
+		url,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calendar snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var days []models.CalendarDay
+	for rows.Next() {
+		var d models.CalendarDay
+		err := rows.Scan(&d.ID, &d.URL, &d.SnapshotDate, &d.Date, &d.Available,
+			&d.CheckIn, &d.CheckOut, &d.MinNights, &d.MaxNights)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan calendar day: %w", err)
+		}
+		days = append(days, d)
+	}
+
+	return days, rows.Err()
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/models"
+	"github.com/farhanasfar/airbnb-market-scraping-system/utils"
+)
+
+// ErrCrawlRunNotFound is returned when no run has the requested ID
+var ErrCrawlRunNotFound = errors.New("crawl run not found")
+
+// CreateCrawlRun records the start of a new run
+func (db *DB) CreateCrawlRun(id string) (*models.CrawlRun, error) {
+	run := &models.CrawlRun{ID: id}
+	err := db.conn.QueryRow(
+		`INSERT INTO crawl_runs (id) VALUES ($1) RETURNING status, created_at, updated_at`,
+		id,
+	).Scan(&run.Status, &run.CreatedAt, &run.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create crawl run: %w", err)
+	}
+	return run, nil
+}
+
+// GetCrawlRun loads a run by ID
+func (db *DB) GetCrawlRun(id string) (*models.CrawlRun, error) {
+	run := &models.CrawlRun{}
+	err := db.conn.QueryRow(
+		`SELECT id, status, created_at, updated_at FROM crawl_runs WHERE id = $1`,
+		id,
+	).Scan(&run.ID, &run.Status, &run.CreatedAt, &run.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrCrawlRunNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get crawl run: %w", err)
+	}
+	return run, nil
+}
+
+// UpdateCrawlRunStatus sets a run's status
+func (db *DB) UpdateCrawlRunStatus(id, status string) error {
+	if _, err := db.conn.Exec(`UPDATE crawl_runs SET status = $2 WHERE id = $1`, id, status); err != nil {
+		return fmt.Errorf("failed to update crawl run: %w", err)
+	}
+	return nil
+}
+
+// InsertCrawlLocations records the locations a run will scrape
+func (db *DB) InsertCrawlLocations(runID string, locations []models.CrawlLocation) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, loc := range locations {
+		_, err := tx.Exec(`
+			INSERT INTO crawl_locations (run_id, position, name, url)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (run_id, position) DO NOTHING`,
+			runID, loc.Position, loc.Name, loc.URL,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert crawl location: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save crawl locations: %w", err)
+	}
+	return nil
+}
+
+// GetCrawlLocations returns a run's locations in discovery order
+func (db *DB) GetCrawlLocations(runID string) ([]models.CrawlLocation, error) {
+	rows, err := db.conn.Query(`
+		SELECT position, name, url, pages_done, next_url, found, stale_pages, done
+		FROM crawl_locations
+		WHERE run_id = $1
+		ORDER BY position`,
+		runID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query crawl locations: %w", err)
+	}
+	defer rows.Close()
+
+	var locations []models.CrawlLocation
+	for rows.Next() {
+		var loc models.CrawlLocation
+		if err := rows.Scan(&loc.Position, &loc.Name, &loc.URL, &loc.PagesDone, &loc.NextURL,
+			&loc.Found, &loc.StalePages, &loc.Done); err != nil {
+			return nil, fmt.Errorf("failed to scan crawl location: %w", err)
+		}
+		locations = append(locations, loc)
+	}
+
+	return locations, rows.Err()
+}
+
+// SaveCrawlPage stores the listings of one results page and the location's progress
+// in a single transaction. An empty NextURL marks the location done.
+func (db *DB) SaveCrawlPage(runID string, location models.CrawlLocation, listings []models.RawListing) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, listing := range listings {
+		data, err := json.Marshal(listing)
+		if err != nil {
+			return fmt.Errorf("failed to encode listing: %w", err)
+		}
+		_, err = tx.Exec(`
+			INSERT INTO crawl_listings (run_id, url, data)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (run_id, url) DO NOTHING`,
+			runID, utils.NormalizeURL(listing.URL), string(data),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert crawl listing: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE crawl_locations
+		SET pages_done = $3, next_url = $4, found = $5, stale_pages = $6, done = $7
+		WHERE run_id = $1 AND position = $2`,
+		runID, location.Position, location.PagesDone, location.NextURL,
+		location.Found, location.StalePages, location.NextURL == "",
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update crawl location: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save crawl page: %w", err)
+	}
+	return nil
+}
+
+// FinishCrawlLocation marks a location done
+func (db *DB) FinishCrawlLocation(runID string, position int) error {
+	_, err := db.conn.Exec(
+		`UPDATE crawl_locations SET done = TRUE, next_url = '' WHERE run_id = $1 AND position = $2`,
+		runID, position,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to finish crawl location: %w", err)
+	}
+	return nil
+}
+
+// GetPendingCrawlListings returns a run's raw listings whose detail pages are not
+// merged yet, in the order they were scraped
+func (db *DB) GetPendingCrawlListings(runID string) ([]models.RawListing, error) {
+	rows, err := db.conn.Query(
+		`SELECT data FROM crawl_listings WHERE run_id = $1 AND NOT detailed ORDER BY id`,
+		runID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query crawl listings: %w", err)
+	}
+	defer rows.Close()
+
+	var listings []models.RawListing
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan crawl listing: %w", err)
+		}
+		var listing models.RawListing
+		if err := json.Unmarshal(data, &listing); err != nil {
+			return nil, fmt.Errorf("failed to decode crawl listing: %w", err)
+		}
+		listings = append(listings, listing)
+	}
+
+	return listings, rows.Err()
+}
+
+// SaveCrawlDetail stores a listing with its detail page merged in
+func (db *DB) SaveCrawlDetail(runID string, listing models.RawListing) error {
+	data, err := json.Marshal(listing)
+	if err != nil {
+		return fmt.Errorf("failed to encode listing: %w", err)
+	}
+
+	_, err = db.conn.Exec(
+		`UPDATE crawl_listings SET data = $3, detailed = TRUE WHERE run_id = $1 AND url = $2`,
+		runID, utils.NormalizeURL(listing.URL), string(data),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save crawl detail: %w", err)
+	}
+	return nil
+}
@@ -2,11 +2,12 @@ package storage
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 
 	"github.com/farhanasfar/airbnb-market-scraping-system/models"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // DB wraps the database connection
@@ -43,6 +44,21 @@ func (db *DB) migrate() error {
 	if _, err := db.conn.Exec(CreateListingsTableSQL); err != nil {
 		return fmt.Errorf("failed to create listings table: %w", err)
 	}
+	if _, err := db.conn.Exec(CreateCrawlTablesSQL); err != nil {
+		return fmt.Errorf("failed to create crawl tables: %w", err)
+	}
+	if _, err := db.conn.Exec(CreateListingPricesTableSQL); err != nil {
+		return fmt.Errorf("failed to create listing prices table: %w", err)
+	}
+	if _, err := db.conn.Exec(CreatePriceQuotesTableSQL); err != nil {
+		return fmt.Errorf("failed to create price quotes table: %w", err)
+	}
+	if _, err := db.conn.Exec(CreateReviewsTableSQL); err != nil {
+		return fmt.Errorf("failed to create reviews table: %w", err)
+	}
+	if _, err := db.conn.Exec(CreateCalendarTableSQL); err != nil {
+		return fmt.Errorf("failed to create calendar table: %w", err)
+	}
 
 	// Create triggers
 	if _, err := db.conn.Exec(UpdateUpdatedAtTriggerSQL); err != nil {
@@ -52,24 +68,40 @@ func (db *DB) migrate() error {
 	return nil
 }
 
-// InsertListing inserts a new listing or updates if URL already exists
+// InsertListing inserts a new listing or updates if URL already exists.
+// Ratings, room counts, coordinates and place names a search card lacks keep their
+// saved values, so re-saving a card does not wipe what its detail page filled in.
 func (db *DB) InsertListing(listing *models.Listing) error {
+	searchParams, err := encodeSearchParams(listing.SearchParams)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO listings (title, price, location, rating, url, bedrooms, bathrooms, guests)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO listings (title, price, location, rating, url, bedrooms, bathrooms, guests, search_params,
+			latitude, longitude, neighborhood, city, region, country, currency)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 		ON CONFLICT (url) DO UPDATE SET
 			title = EXCLUDED.title,
 			price = EXCLUDED.price,
+			currency = EXCLUDED.currency,
 			location = EXCLUDED.location,
-			rating = EXCLUDED.rating,
-			bedrooms = EXCLUDED.bedrooms,
-			bathrooms = EXCLUDED.bathrooms,
-			guests = EXCLUDED.guests,
+			rating = COALESCE(NULLIF(EXCLUDED.rating, 0), listings.rating),
+			bedrooms = COALESCE(NULLIF(EXCLUDED.bedrooms, 0), listings.bedrooms),
+			bathrooms = COALESCE(NULLIF(EXCLUDED.bathrooms, 0), listings.bathrooms),
+			guests = COALESCE(NULLIF(EXCLUDED.guests, 0), listings.guests),
+			search_params = EXCLUDED.search_params,
+			latitude = COALESCE(NULLIF(EXCLUDED.latitude, 0), listings.latitude),
+			longitude = COALESCE(NULLIF(EXCLUDED.longitude, 0), listings.longitude),
+			neighborhood = COALESCE(NULLIF(EXCLUDED.neighborhood, ''), listings.neighborhood),
+			city = COALESCE(NULLIF(EXCLUDED.city, ''), listings.city),
+			region = COALESCE(NULLIF(EXCLUDED.region, ''), listings.region),
+			country = COALESCE(NULLIF(EXCLUDED.country, ''), listings.country),
 			updated_at = CURRENT_TIMESTAMP
 		RETURNING id
 	`
 
-	err := db.conn.QueryRow(
+	err = db.conn.QueryRow(
 		query,
 		listing.Title,
 		listing.Price,
@@ -79,6 +111,14 @@ func (db *DB) InsertListing(listing *models.Listing) error {
 		listing.Bedrooms,
 		listing.Bathrooms,
 		listing.Guests,
+		searchParams,
+		listing.Latitude,
+		listing.Longitude,
+		listing.Neighborhood,
+		listing.City,
+		listing.Region,
+		listing.Country,
+		listing.Currency,
 	).Scan(&listing.ID)
 
 	if err != nil {
@@ -88,10 +128,87 @@ func (db *DB) InsertListing(listing *models.Listing) error {
 	return nil
 }
 
+// UpdateListingDetails patches the fields scraped from a listing's detail page
+// into the saved listing with the same URL.
+// InsertListing only overwrites the room counts and rating with non-zero card
+// values and leaves the other columns alone, so re-saving a search card keeps them.
+func (db *DB) UpdateListingDetails(listing *models.Listing) error {
+	query := `
+		UPDATE listings SET
+			rating = $2,
+			bedrooms = $3,
+			bathrooms = $4,
+			guests = $5,
+			property_type = $6,
+			room_type = $7,
+			beds = $8,
+			amenities = $9,
+			host_name = $10,
+			host_id = $11,
+			superhost = $12,
+			host_join_year = $13,
+			host_response_rate = $14,
+			check_in_time = $15,
+			check_out_time = $16,
+			min_nights = $17,
+			cancellation_policy = $18,
+			instant_book = $19,
+			latitude = COALESCE(NULLIF($20::DOUBLE PRECISION, 0), latitude),
+			longitude = COALESCE(NULLIF($21::DOUBLE PRECISION, 0), longitude),
+			neighborhood = COALESCE(NULLIF($22, ''), neighborhood),
+			city = COALESCE(NULLIF($23, ''), city),
+			region = COALESCE(NULLIF($24, ''), region),
+			country = COALESCE(NULLIF($25, ''), country),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE url = $1
+		RETURNING id
+	`
+
+	details := listing.ListingDetails
+	err := db.conn.QueryRow(
+		query,
+		listing.URL,
+		listing.Rating,
+		listing.Bedrooms,
+		listing.Bathrooms,
+		listing.Guests,
+		details.PropertyType,
+		details.RoomType,
+		details.Beds,
+		pq.Array(nonNil(details.Amenities)),
+		details.HostName,
+		details.HostID,
+		details.Superhost,
+		details.HostJoinYear,
+		details.HostResponseRate,
+		details.CheckInTime,
+		details.CheckOutTime,
+		details.MinNights,
+		details.CancellationPolicy,
+		details.InstantBook,
+		listing.Latitude,
+		listing.Longitude,
+		listing.Neighborhood,
+		listing.City,
+		listing.Region,
+		listing.Country,
+	).Scan(&listing.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to update listing details: %w", err)
+	}
+
+	return nil
+}
+
 // GetAllListings retrieves all listings from the database
 func (db *DB) GetAllListings() ([]models.Listing, error) {
 	query := `
-		SELECT id, title, price, location, rating, url, bedrooms, bathrooms, guests, created_at, updated_at
+		SELECT id, title, price, currency, location, rating, url, bedrooms, bathrooms, guests, search_params,
+			latitude, longitude, neighborhood, city, region, country,
+			property_type, room_type, beds, amenities, host_name, host_id, superhost, host_join_year,
+			host_response_rate, check_in_time, check_out_time, min_nights, cancellation_policy, instant_book,
+			created_at, updated_at
 		FROM listings
 		ORDER BY created_at DESC
 	`
@@ -105,20 +222,49 @@ func (db *DB) GetAllListings() ([]models.Listing, error) {
 	var listings []models.Listing
 	for rows.Next() {
 		var l models.Listing
+		var searchParams []byte
+		d := &l.ListingDetails
 		err := rows.Scan(
-			&l.ID, &l.Title, &l.Price, &l.Location, &l.Rating,
-			&l.URL, &l.Bedrooms, &l.Bathrooms, &l.Guests,
+			&l.ID, &l.Title, &l.Price, &l.Currency, &l.Location, &l.Rating,
+			&l.URL, &l.Bedrooms, &l.Bathrooms, &l.Guests, &searchParams,
+			&l.Latitude, &l.Longitude, &l.Neighborhood, &l.City, &l.Region, &l.Country,
+			&d.PropertyType, &d.RoomType, &d.Beds, pq.Array(&d.Amenities), &d.HostName, &d.HostID,
+			&d.Superhost, &d.HostJoinYear, &d.HostResponseRate, &d.CheckInTime, &d.CheckOutTime,
+			&d.MinNights, &d.CancellationPolicy, &d.InstantBook,
 			&l.CreatedAt, &l.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan listing: %w", err)
 		}
+		if err := json.Unmarshal(searchParams, &l.SearchParams); err != nil {
+			return nil, fmt.Errorf("failed to decode search params: %w", err)
+		}
 		listings = append(listings, l)
 	}
 
 	return listings, nil
 }
 
+// encodeSearchParams returns search parameters as JSON text for a JSONB column
+func encodeSearchParams(params map[string]string) (string, error) {
+	if params == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode search params: %w", err)
+	}
+	return string(data), nil
+}
+
+// nonNil returns an empty slice for nil so NOT NULL array columns get '{}'
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
 // close the database connection
 func (db *DB) Close() error {
 	return db.conn.Close()
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/models"
+)
+
+// UpsertListingPrice records the price of a listing for a stay, replacing an
+// earlier observation of the same stay
+func (db *DB) UpsertListingPrice(price *models.ListingPrice) error {
+	query := `
+		INSERT INTO listing_prices (url, check_in, check_out, nights, price, nightly_price, currency)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (url, check_in, check_out) DO UPDATE SET
+			nights = EXCLUDED.nights,
+			price = EXCLUDED.price,
+			nightly_price = EXCLUDED.nightly_price,
+			currency = EXCLUDED.currency,
+			observed_at = CURRENT_TIMESTAMP
+		RETURNING id, observed_at
+	`
+
+	err := db.conn.QueryRow(
+		query,
+		price.URL,
+		price.CheckIn,
+		price.CheckOut,
+		price.Nights,
+		price.Price,
+		price.NightlyPrice,
+		price.Currency,
+	).Scan(&price.ID, &price.ObservedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to save listing price: %w", err)
+	}
+
+	return nil
+}
+
+// GetListingPrices returns a listing's observed prices ordered by check-in,
+// the listing's seasonal price curve
+func (db *DB) GetListingPrices(url string) ([]models.ListingPrice, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, url, check_in, check_out, nights, price, nightly_price, currency, observed_at
+		FROM listing_prices
+		WHERE url = $1
+		ORDER BY check_in, nights`,
+		url,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query listing prices: %w", err)
+	}
+	defer rows.Close()
+
+	var prices []models.ListingPrice
+	for rows.Next() {
+		var p models.ListingPrice
+		err := rows.Scan(&p.ID, &p.URL, &p.CheckIn, &p.CheckOut, &p.Nights,
+			&p.Price, &p.NightlyPrice, &p.Currency, &p.ObservedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan listing price: %w", err)
+		}
+		prices = append(prices, p)
+	}
+
+	return prices, rows.Err()
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/models"
+)
+
+// UpsertPriceQuote records the price breakdown of a listing for a stay, replacing
+// an earlier quote of the same stay and guest count
+func (db *DB) UpsertPriceQuote(quote *models.PriceQuote) error {
+	query := `
+		INSERT INTO price_quotes (url, check_in, check_out, guests, nights, nightly_rate,
+			original_price, discounted_price, cleaning_fee, service_fee, taxes, total, currency)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (url, check_in, check_out, guests) DO UPDATE SET
+			nights = EXCLUDED.nights,
+			nightly_rate = EXCLUDED.nightly_rate,
+			original_price = EXCLUDED.original_price,
+			discounted_price = EXCLUDED.discounted_price,
+			cleaning_fee = EXCLUDED.cleaning_fee,
+			service_fee = EXCLUDED.service_fee,
+			taxes = EXCLUDED.taxes,
+			total = EXCLUDED.total,
+			currency = EXCLUDED.currency,
+			observed_at = CURRENT_TIMESTAMP
+		RETURNING id, observed_at
+	`
+
+	err := db.conn.QueryRow(
+		query,
+		quote.URL,
+		quote.CheckIn,
+		quote.CheckOut,
+		quote.Guests,
+		quote.Nights,
+		quote.NightlyRate,
+		quote.OriginalPrice,
+		quote.DiscountedPrice,
+		quote.CleaningFee,
+		quote.ServiceFee,
+		quote.Taxes,
+		quote.Total,
+		quote.Currency,
+	).Scan(&quote.ID, &quote.ObservedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to save price quote: %w", err)
+	}
+
+	return nil
+}
+
+// GetPriceQuotes returns a listing's price quotes ordered by check-in
+func (db *DB) GetPriceQuotes(url string) ([]models.PriceQuote, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, url, check_in, check_out, guests, nights, nightly_rate, original_price,
+			discounted_price, cleaning_fee, service_fee, taxes, total, currency, observed_at
+		FROM price_quotes
+		WHERE url = $1
+		ORDER BY check_in, guests`,
+		url,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price quotes: %w", err)
+	}
+	defer rows.Close()
+
+	var quotes []models.PriceQuote
+	for rows.Next() {
+		var q models.PriceQuote
+		err := rows.Scan(&q.ID, &q.URL, &q.CheckIn, &q.CheckOut, &q.Guests, &q.Nights,
+			&q.NightlyRate, &q.OriginalPrice, &q.DiscountedPrice, &q.CleaningFee,
+			&q.ServiceFee, &q.Taxes, &q.Total, &q.Currency, &q.ObservedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan price quote: %w", err)
+		}
+		quotes = append(quotes, q)
+	}
+
+	return quotes, rows.Err()
+}
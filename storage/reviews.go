@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/farhanasfar/airbnb-market-scraping-system/models"
+)
+
+// UpsertReview saves a review, updating the text and host response of one
+// already stored since hosts can respond after the review was first scraped
+func (db *DB) UpsertReview(review *models.Review) error {
+	query := `
+		INSERT INTO reviews (url, review_id, reviewer_name, reviewed_at, language, text, host_response)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (url, review_id) DO UPDATE SET
+			reviewer_name = EXCLUDED.reviewer_name,
+			language = EXCLUDED.language,
+			text = EXCLUDED.text,
+			host_response = EXCLUDED.host_response,
+			scraped_at = CURRENT_TIMESTAMP
+		RETURNING id, scraped_at
+	`
+
+	var reviewedAt sql.NullTime
+	if !review.ReviewedAt.IsZero() {
+		reviewedAt = sql.NullTime{Time: review.ReviewedAt, Valid: true}
+	}
+
+	err := db.conn.QueryRow(
+		query,
+		review.URL,
+		review.ReviewID,
+		review.ReviewerName,
+		reviewedAt,
+		review.Language,
+		review.Text,
+		review.HostResponse,
+	).Scan(&review.ID, &review.ScrapedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to save review: %w", err)
+	}
+
+	return nil
+}
+
+// LatestReviewDate returns the date of a listing's newest stored review,
+// or the zero time when none is stored
+func (db *DB) LatestReviewDate(url string) (time.Time, error) {
+	var latest sql.NullTime
+	err := db.conn.QueryRow(`SELECT MAX(reviewed_at) FROM reviews WHERE url = $1`, url).Scan(&latest)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to query latest review: %w", err)
+	}
+	return latest.Time, nil
+}
+
+// GetReviews returns a listing's stored reviews, newest first
+func (db *DB) GetReviews(url string) ([]models.Review, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, url, review_id, reviewer_name, reviewed_at, language, text, host_response, scraped_at
+		FROM reviews
+		WHERE url = $1
+		ORDER BY reviewed_at DESC NULLS LAST, id DESC`,
+		url,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reviews: %w", err)
+	}
+	defer rows.Close()
+
+	var reviews []models.Review
+	for rows.Next() {
+		var r models.Review
+		var reviewedAt sql.NullTime
+		err := rows.Scan(&r.ID, &r.URL, &r.ReviewID, &r.ReviewerName, &reviewedAt,
+			&r.Language, &r.Text, &r.HostResponse, &r.ScrapedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review: %w", err)
+		}
+		r.ReviewedAt = reviewedAt.Time
+		reviews = append(reviews, r)
+	}
+
+	return reviews, rows.Err()
+}
@@ -7,22 +7,75 @@ const (
 		id SERIAL PRIMARY KEY,
 		title TEXT NOT NULL,
 		price DECIMAL(10, 2) NOT NULL,
+		currency TEXT NOT NULL DEFAULT '',
 		location TEXT NOT NULL,
 		rating DECIMAL(3, 2) DEFAULT 0.0,
 		url TEXT UNIQUE NOT NULL, 
 		bedrooms INTEGER DEFAULT 0,
 		bathrooms INTEGER DEFAULT 0,
 		guests INTEGER DEFAULT 0,
+		search_params JSONB NOT NULL DEFAULT '{}',
+		latitude DOUBLE PRECISION DEFAULT 0,
+		longitude DOUBLE PRECISION DEFAULT 0,
+		neighborhood TEXT NOT NULL DEFAULT '',
+		city TEXT NOT NULL DEFAULT '',
+		region TEXT NOT NULL DEFAULT '',
+		country TEXT NOT NULL DEFAULT '',
+		property_type TEXT NOT NULL DEFAULT '',
+		room_type TEXT NOT NULL DEFAULT '',
+		beds INTEGER DEFAULT 0,
+		amenities TEXT[] NOT NULL DEFAULT '{}',
+		host_name TEXT NOT NULL DEFAULT '',
+		host_id TEXT NOT NULL DEFAULT '',
+		superhost BOOLEAN NOT NULL DEFAULT FALSE,
+		host_join_year INTEGER DEFAULT 0,
+		host_response_rate INTEGER DEFAULT 0,
+		check_in_time TEXT NOT NULL DEFAULT '',
+		check_out_time TEXT NOT NULL DEFAULT '',
+		min_nights INTEGER DEFAULT 0,
+		cancellation_policy TEXT NOT NULL DEFAULT '',
+		instant_book BOOLEAN NOT NULL DEFAULT FALSE,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);
 
+	-- Columns added after the table was first created
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS search_params JSONB NOT NULL DEFAULT '{}';
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS property_type TEXT NOT NULL DEFAULT '';
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS room_type TEXT NOT NULL DEFAULT '';
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS beds INTEGER DEFAULT 0;
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS amenities TEXT[] NOT NULL DEFAULT '{}';
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS host_name TEXT NOT NULL DEFAULT '';
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS host_id TEXT NOT NULL DEFAULT '';
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS superhost BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS host_join_year INTEGER DEFAULT 0;
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS host_response_rate INTEGER DEFAULT 0;
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS check_in_time TEXT NOT NULL DEFAULT '';
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS check_out_time TEXT NOT NULL DEFAULT '';
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS min_nights INTEGER DEFAULT 0;
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS cancellation_policy TEXT NOT NULL DEFAULT '';
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS instant_book BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION DEFAULT 0;
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION DEFAULT 0;
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS neighborhood TEXT NOT NULL DEFAULT '';
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS city TEXT NOT NULL DEFAULT '';
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS region TEXT NOT NULL DEFAULT '';
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS country TEXT NOT NULL DEFAULT '';
+	ALTER TABLE listings ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT '';
+
 	-- Index on price for analytics queries (avg, min, max)
 	CREATE INDEX IF NOT EXISTS idx_listings_price ON listings(price);
 	
 	-- Index on location for location-based queries
 	CREATE INDEX IF NOT EXISTS idx_listings_location ON listings(location);
 	
+	-- Indexes on city and country for market grouping
+	CREATE INDEX IF NOT EXISTS idx_listings_city ON listings(city);
+	CREATE INDEX IF NOT EXISTS idx_listings_country ON listings(country);
+
+	-- Index on host for per-host queries
+	CREATE INDEX IF NOT EXISTS idx_listings_host_id ON listings(host_id);
+
 	-- Index on rating for top-rated queries
 	CREATE INDEX IF NOT EXISTS idx_listings_rating ON listings(rating DESC);
 	
@@ -30,6 +83,130 @@ const (
 	CREATE UNIQUE INDEX IF NOT EXISTS idx_listings_url ON listings(url);
 	`
 
+	// CreateCrawlTablesSQL creates the checkpoint tables for resumable runs
+	CreateCrawlTablesSQL = `
+	CREATE TABLE IF NOT EXISTS crawl_runs (
+		id TEXT PRIMARY KEY,
+		status TEXT NOT NULL DEFAULT 'running',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Locations discovered by a run and how far their results were scraped
+	CREATE TABLE IF NOT EXISTS crawl_locations (
+		run_id TEXT NOT NULL REFERENCES crawl_runs(id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		url TEXT NOT NULL,
+		pages_done INTEGER NOT NULL DEFAULT 0,
+		next_url TEXT NOT NULL DEFAULT '',
+		found INTEGER NOT NULL DEFAULT 0,
+		stale_pages INTEGER NOT NULL DEFAULT 0,
+		done BOOLEAN NOT NULL DEFAULT FALSE,
+		PRIMARY KEY (run_id, position)
+	);
+
+	-- Crawl mode progress, added after the table was first created
+	ALTER TABLE crawl_locations ADD COLUMN IF NOT EXISTS found INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE crawl_locations ADD COLUMN IF NOT EXISTS stale_pages INTEGER NOT NULL DEFAULT 0;
+
+	-- Raw listings scraped by a run, keyed by normalized URL
+	CREATE TABLE IF NOT EXISTS crawl_listings (
+		id SERIAL PRIMARY KEY,
+		run_id TEXT NOT NULL REFERENCES crawl_runs(id) ON DELETE CASCADE,
+		url TEXT NOT NULL,
+		data JSONB NOT NULL,
+		detailed BOOLEAN NOT NULL DEFAULT FALSE,
+		UNIQUE (run_id, url)
+	);
+	`
+
+	// CreateListingPricesTableSQL creates the table of prices observed for specific stays
+	CreateListingPricesTableSQL = `
+	CREATE TABLE IF NOT EXISTS listing_prices (
+		id SERIAL PRIMARY KEY,
+		url TEXT NOT NULL REFERENCES listings(url) ON DELETE CASCADE,
+		check_in DATE NOT NULL,
+		check_out DATE NOT NULL,
+		nights INTEGER NOT NULL,
+		price DECIMAL(10, 2) NOT NULL,
+		nightly_price DECIMAL(10, 2) NOT NULL,
+		currency TEXT NOT NULL DEFAULT '',
+		observed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (url, check_in, check_out)
+	);
+
+	-- Index on check-in date for seasonal price curves
+	CREATE INDEX IF NOT EXISTS idx_listing_prices_check_in ON listing_prices(check_in);
+
+	ALTER TABLE listing_prices ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT '';
+	`
+
+	// CreatePriceQuotesTableSQL creates the table of price breakdowns quoted by detail pages
+	CreatePriceQuotesTableSQL = `
+	CREATE TABLE IF NOT EXISTS price_quotes (
+		id SERIAL PRIMARY KEY,
+		url TEXT NOT NULL REFERENCES listings(url) ON DELETE CASCADE,
+		check_in DATE NOT NULL,
+		check_out DATE NOT NULL,
+		guests INTEGER NOT NULL,
+		nights INTEGER NOT NULL,
+		nightly_rate DECIMAL(10, 2) DEFAULT 0,
+		original_price DECIMAL(10, 2) DEFAULT 0,
+		discounted_price DECIMAL(10, 2) DEFAULT 0,
+		cleaning_fee DECIMAL(10, 2) DEFAULT 0,
+		service_fee DECIMAL(10, 2) DEFAULT 0,
+		taxes DECIMAL(10, 2) DEFAULT 0,
+		total DECIMAL(10, 2) NOT NULL,
+		currency TEXT NOT NULL DEFAULT '',
+		observed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (url, check_in, check_out, guests)
+	);
+
+	-- Index on check-in date for comparing quotes of the same stay
+	CREATE INDEX IF NOT EXISTS idx_price_quotes_check_in ON price_quotes(check_in);
+
+	ALTER TABLE price_quotes ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT '';
+	`
+
+	// CreateReviewsTableSQL creates the table of guest reviews per listing
+	CreateReviewsTableSQL = `
+	CREATE TABLE IF NOT EXISTS reviews (
+		id SERIAL PRIMARY KEY,
+		url TEXT NOT NULL REFERENCES listings(url) ON DELETE CASCADE,
+		review_id TEXT NOT NULL,
+		reviewer_name TEXT NOT NULL DEFAULT '',
+		reviewed_at TIMESTAMP,
+		language TEXT NOT NULL DEFAULT '',
+		text TEXT NOT NULL DEFAULT '',
+		host_response TEXT NOT NULL DEFAULT '',
+		scraped_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (url, review_id)
+	);
+
+	-- Index on listing and date for incremental fetching and review velocity
+	CREATE INDEX IF NOT EXISTS idx_reviews_url_reviewed_at ON reviews(url, reviewed_at DESC);
+	`
+
+	// CreateCalendarTableSQL creates the table of daily availability calendar snapshots
+	CreateCalendarTableSQL = `
+	CREATE TABLE IF NOT EXISTS calendar_snapshots (
+		id SERIAL PRIMARY KEY,
+		url TEXT NOT NULL REFERENCES listings(url) ON DELETE CASCADE,
+		snapshot_date DATE NOT NULL,
+		date DATE NOT NULL,
+		available BOOLEAN NOT NULL,
+		check_in BOOLEAN NOT NULL DEFAULT FALSE,
+		check_out BOOLEAN NOT NULL DEFAULT FALSE,
+		min_nights INTEGER DEFAULT 0,
+		max_nights INTEGER DEFAULT 0,
+		UNIQUE (url, snapshot_date, date)
+	);
+
+	-- Index on snapshot date for comparing consecutive snapshots
+	CREATE INDEX IF NOT EXISTS idx_calendar_snapshots_snapshot_date ON calendar_snapshots(snapshot_date);
+	`
+
 	// UpdateUpdatedAtTriggerSQL creates a trigger to auto-update updated_at
 	UpdateUpdatedAtTriggerSQL = `
 	CREATE OR REPLACE FUNCTION update_updated_at_column()
@@ -46,5 +223,12 @@ const (
 		BEFORE UPDATE ON listings
 		FOR EACH ROW
 		EXECUTE FUNCTION update_updated_at_column();
+
+	DROP TRIGGER IF EXISTS update_crawl_runs_updated_at ON crawl_runs;
+
+	CREATE TRIGGER update_crawl_runs_updated_at
+		BEFORE UPDATE ON crawl_runs
+		FOR EACH ROW
+		EXECUTE FUNCTION update_updated_at_column();
 	`
 )
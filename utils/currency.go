@@ -0,0 +1,270 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// currencySymbols maps the symbols Airbnb prints before or after prices to ISO codes.
+// Longer symbols come first so "R$" is not read as "$".
+var currencySymbols = []struct {
+	symbol string
+	code   string
+}{
+	{"US$", "USD"}, {"AU$", "AUD"}, {"A$", "AUD"}, {"CA$", "CAD"}, {"C$", "CAD"},
+	{"NZ$", "NZD"}, {"HK$", "HKD"}, {"MX$", "MXN"}, {"SG$", "SGD"}, {"S$", "SGD"},
+	{"NT$", "TWD"}, {"R$", "BRL"}, {"CN¥", "CNY"}, {"zł", "PLN"}, {"Kč", "CZK"},
+	{"€", "EUR"}, {"£", "GBP"}, {"₹", "INR"}, {"₩", "KRW"}, {"₺", "TRY"}, {"₱", "PHP"},
+	{"฿", "THB"}, {"₫", "VND"}, {"₪", "ILS"}, {"₴", "UAH"}, {"₦", "NGN"},
+}
+
+// sharedSymbols are printed by several currencies; the page's region decides which
+var sharedSymbols = []struct {
+	symbol string
+	codes  map[string]bool
+}{
+	{"$", map[string]bool{"USD": true, "CAD": true, "AUD": true, "NZD": true, "MXN": true, "HKD": true,
+		"SGD": true, "TWD": true, "ARS": true, "CLP": true, "COP": true}},
+	{"¥", map[string]bool{"JPY": true, "CNY": true}},
+	{"kr", map[string]bool{"SEK": true, "NOK": true, "DKK": true, "ISK": true}},
+}
+
+// regionCurrencies maps the regions of Airbnb's country domains to their local currency
+var regionCurrencies = map[string]string{
+	"US": "USD", "CA": "CAD", "AU": "AUD", "NZ": "NZD", "MX": "MXN", "HK": "HKD",
+	"SG": "SGD", "TW": "TWD", "AR": "ARS", "CL": "CLP", "CO": "COP", "JP": "JPY",
+	"CN": "CNY", "SE": "SEK", "NO": "NOK", "DK": "DKK", "IS": "ISK",
+}
+
+// currencyCodes are the ISO codes recognized when a price spells its currency out
+var currencyCodes = map[string]bool{
+	"USD": true, "EUR": true, "GBP": true, "AUD": true, "CAD": true, "NZD": true,
+	"HKD": true, "MXN": true, "SGD": true, "TWD": true, "BRL": true, "CNY": true,
+	"JPY": true, "INR": true, "KRW": true, "TRY": true, "PHP": true, "THB": true,
+	"VND": true, "ILS": true, "UAH": true, "NGN": true, "PLN": true, "CZK": true,
+	"CHF": true, "SEK": true, "NOK": true, "DKK": true, "ISK": true, "HUF": true,
+	"RON": true, "BGN": true, "ZAR": true, "AED": true, "SAR": true, "EGP": true,
+	"MAD": true, "IDR": true, "MYR": true, "CLP": true, "COP": true, "PEN": true,
+	"ARS": true, "CRC": true,
+}
+
+var currencyCodePattern = regexp.MustCompile(`\b[A-Z]{3}\b`)
+
+// DetectCurrency returns the ISO code of the currency a displayed price is in, e.g.
+// "€120" -> "EUR", "£150 AUD" -> "AUD". A spelled-out code wins over a symbol. A
+// symbol several currencies print, such as "$" or "kr", is read as the currency of
+// region ("$120" in CA -> "CAD"). Returns "" when the price names no currency, or a
+// shared symbol the region does not settle.
+func DetectCurrency(raw, region string) string {
+	for _, code := range currencyCodePattern.FindAllString(raw, -1) {
+		if currencyCodes[code] {
+			return code
+		}
+	}
+	for _, s := range currencySymbols {
+		if containsSymbol(raw, s.symbol) {
+			return s.code
+		}
+	}
+	for _, s := range sharedSymbols {
+		if containsSymbol(raw, s.symbol) {
+			if code := regionCurrencies[region]; s.codes[code] {
+				return code
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// PriceCurrency returns the ISO code of a price shown on a page. The currency code
+// of the page's embedded state wins; otherwise the price is read with DetectCurrency
+// in the region of the page locale, or of the page's domain when the locale names
+// none, so "$120" is CAD on airbnb.ca even with lang="fr".
+func PriceCurrency(raw, stateCode string, locale Locale, pageURL string) string {
+	if code := strings.ToUpper(stateCode); currencyCodes[code] {
+		return code
+	}
+	region := locale.Region()
+	if region == "" {
+		if parsed, err := url.Parse(pageURL); err == nil {
+			if host, ok := LocaleFromHost(parsed.Hostname()); ok {
+				region = host.Region()
+			}
+		}
+	}
+	return DetectCurrency(raw, region)
+}
+
+// IsCurrencyCode reports whether code is an ISO currency code prices are stored in
+func IsCurrencyCode(code string) bool {
+	return currencyCodes[code]
+}
+
+// containsSymbol reports whether raw has symbol not preceded by a letter,
+// so "CA$" is not read as "A$"
+func containsSymbol(raw, symbol string) bool {
+	for offset := 0; ; {
+		i := strings.Index(raw[offset:], symbol)
+		if i < 0 {
+			return false
+		}
+		i += offset
+		if before, _ := utf8.DecodeLastRuneInString(raw[:i]); i == 0 || !unicode.IsLetter(before) {
+			return true
+		}
+		offset = i + len(symbol)
+	}
+}
+
+// ExchangeRates converts amounts between currencies using rates read from a local file.
+// Each rate is how many units of a currency one unit of the base currency buys.
+type ExchangeRates struct {
+	Base    string
+	current map[string]float64
+	history []datedRates // oldest first
+}
+
+type datedRates struct {
+	date  time.Time
+	rates map[string]float64
+}
+
+// ratesFile is the layout of the exchange rates file
+type ratesFile struct {
+	Base    string                        `yaml:"base"`
+	Rates   map[string]float64            `yaml:"rates"`
+	History map[string]map[string]float64 `yaml:"history"` // rates by the YYYY-MM-DD date they took effect
+}
+
+// LoadExchangeRates reads an exchange rates file
+func LoadExchangeRates(path string) (*ExchangeRates, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exchange rates: %w", err)
+	}
+
+	var file ratesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse exchange rates: %w", err)
+	}
+	if file.Base == "" {
+		return nil, fmt.Errorf("exchange rates %s have no base currency", path)
+	}
+
+	rates := &ExchangeRates{Base: strings.ToUpper(file.Base), current: upperKeys(file.Rates)}
+	for day, dayRates := range file.History {
+		date, err := time.Parse("2006-01-02", day)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exchange rate date %q in %s (use YYYY-MM-DD)", day, path)
+		}
+		rates.history = append(rates.history, datedRates{date: date, rates: upperKeys(dayRates)})
+	}
+	sort.Slice(rates.history, func(i, j int) bool {
+		return rates.history[i].date.Before(rates.history[j].date)
+	})
+
+	for code, rate := range rates.current {
+		if rate <= 0 {
+			return nil, fmt.Errorf("exchange rate of %s in %s must be positive", code, path)
+		}
+	}
+	return rates, nil
+}
+
+func upperKeys(rates map[string]float64) map[string]float64 {
+	upper := make(map[string]float64, len(rates))
+	for code, rate := range rates {
+		upper[strings.ToUpper(code)] = rate
+	}
+	return upper
+}
+
+// rate returns the rate of a currency on a day: the latest dated rate that took
+// effect by then, or the current rate. The zero day always uses the current rate.
+func (r *ExchangeRates) rate(code string, on time.Time) (float64, bool) {
+	if code == r.Base {
+		return 1, true
+	}
+	if !on.IsZero() {
+		for i := len(r.history) - 1; i >= 0; i-- {
+			if r.history[i].date.After(on) {
+				continue
+			}
+			if rate, ok := r.history[i].rates[code]; ok && rate > 0 {
+				return rate, true
+			}
+		}
+	}
+	rate, ok := r.current[code]
+	return rate, ok && rate > 0
+}
+
+// Convert converts an amount from one currency to another at the rates of a day
+func (r *ExchangeRates) Convert(amount float64, from, to string, on time.Time) (float64, error) {
+	if from == to {
+		return amount, nil
+	}
+	if r == nil {
+		return 0, fmt.Errorf("no exchange rates loaded to convert %s to %s", from, to)
+	}
+	fromRate, ok := r.rate(from, on)
+	if !ok {
+		return 0, fmt.Errorf("no exchange rate for %s", from)
+	}
+	toRate, ok := r.rate(to, on)
+	if !ok {
+		return 0, fmt.Errorf("no exchange rate for %s", to)
+	}
+	return amount / fromRate * toRate, nil
+}
+
+// CurrencyConverter reports prices in one target currency
+type CurrencyConverter struct {
+	Target   string // currency reports are in
+	Fallback string // currency of prices stored without one
+	rates    *ExchangeRates
+}
+
+// NewCurrencyConverter reports in target, reading prices without a currency as
+// fallback. The rates may be nil when every price is already in the target currency.
+func NewCurrencyConverter(target, fallback string, rates *ExchangeRates) *CurrencyConverter {
+	if fallback == "" {
+		fallback = "USD"
+	}
+	if target == "" {
+		target = fallback
+	}
+	return &CurrencyConverter{
+		Target:   strings.ToUpper(target),
+		Fallback: strings.ToUpper(fallback),
+		rates:    rates,
+	}
+}
+
+// Convert converts an amount observed on a day into the target currency
+func (c *CurrencyConverter) Convert(amount float64, currency string, on time.Time) (float64, error) {
+	return c.rates.Convert(amount, c.Currency(currency), c.Target, on)
+}
+
+// Currency returns the currency a stored price is in, applying the fallback
+func (c *CurrencyConverter) Currency(currency string) string {
+	if currency == "" {
+		return c.Fallback
+	}
+	return currency
+}
+
+// FormatMoney formats an amount with its currency code, e.g. "EUR 120.00"
+func FormatMoney(amount float64, currency string) string {
+	return fmt.Sprintf("%s %.2f", currency, amount)
+}
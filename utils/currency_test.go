@@ -0,0 +1,36 @@
+package utils
+
+import "testing"
+
+func TestPriceCurrency(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		stateCode string
+		locale    string
+		url       string
+		want      string
+	}{
+		{"dollar on airbnb.com", "$120", "", "en-US", "https://www.airbnb.com/rooms/1", "USD"},
+		{"dollar on airbnb.ca", "$120", "", "en-CA", "https://www.airbnb.ca/rooms/1", "CAD"},
+		{"dollar on airbnb.ca in french", "120 $", "", "fr", "https://www.airbnb.ca/rooms/1", "CAD"},
+		{"dollar on airbnb.com.au", "$180 total", "", "en-AU", "https://www.airbnb.com.au/rooms/1", "AUD"},
+		{"dollar on airbnb.mx", "$1,850", "", "es-MX", "https://www.airbnb.mx/rooms/1", "MXN"},
+		{"state code wins", "$120", "cad", "en-US", "https://www.airbnb.com/rooms/1", "CAD"},
+		{"spelled-out code wins over the region", "$120 USD", "", "en-CA", "https://www.airbnb.ca/rooms/1", "USD"},
+		{"own symbol wins over the region", "CA$120", "", "en-US", "https://www.airbnb.com/rooms/1", "CAD"},
+		{"crowns in sweden", "1 234 kr", "", "sv-SE", "https://www.airbnb.se/rooms/1", "SEK"},
+		{"dollar in a region without one", "$120", "", "de-DE", "https://www.airbnb.de/rooms/1", ""},
+		{"dollar with no region", "$120", "", "en", "https://example.com/rooms/1", ""},
+		{"euro anywhere", "120 €", "", "en-CA", "https://www.airbnb.ca/rooms/1", "EUR"},
+		{"no currency", "120", "", "en-US", "https://www.airbnb.com/rooms/1", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PriceCurrency(tt.raw, tt.stateCode, ParseLocale(tt.locale), tt.url); got != tt.want {
+				t.Errorf("PriceCurrency(%q, %q, %s, %s) = %q, want %q", tt.raw, tt.stateCode, tt.locale, tt.url, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Locale is how a page writes numbers: which of "." and "," separates decimals
+type Locale struct {
+	Tag     string // BCP 47 tag, e.g. "de-DE"
+	Decimal rune   // '.' or ','
+}
+
+// DefaultLocale is used when a page names no locale: airbnb.com's US English
+var DefaultLocale = Locale{Tag: "en-US", Decimal: '.'}
+
+// commaDecimalLanguages write "1.234,56"
+var commaDecimalLanguages = map[string]bool{
+	"de": true, "fr": true, "es": true, "it": true, "pt": true, "nl": true,
+	"da": true, "sv": true, "nb": true, "no": true, "nn": true, "fi": true,
+	"is": true, "pl": true, "cs": true, "sk": true, "sl": true, "hr": true,
+	"sr": true, "bs": true, "hu": true, "ro": true, "bg": true, "ru": true,
+	"uk": true, "be": true, "lt": true, "lv": true, "et": true, "el": true,
+	"tr": true, "ca": true, "eu": true, "gl": true, "id": true, "vi": true,
+	"az": true, "ka": true, "kk": true,
+}
+
+// dotDecimalTags are regions of comma languages that write "1,234.56" or "1'234.56"
+var dotDecimalTags = map[string]bool{
+	"es-MX": true, "es-US": true, "es-419": true, "de-CH": true, "it-CH": true, "de-LI": true,
+}
+
+// domainLocales maps Airbnb country domains to the locale their pages use
+var domainLocales = map[string]string{
+	"com": "en-US", "co.uk": "en-GB", "ie": "en-IE", "ca": "en-CA", "com.au": "en-AU",
+	"co.nz": "en-NZ", "co.in": "en-IN", "com.sg": "en-SG", "co.za": "en-ZA",
+	"de": "de-DE", "at": "de-AT", "ch": "de-CH", "fr": "fr-FR", "be": "fr-BE",
+	"es": "es-ES", "it": "it-IT", "nl": "nl-NL", "pt": "pt-PT", "com.br": "pt-BR",
+	"dk": "da-DK", "se": "sv-SE", "no": "nb-NO", "fi": "fi-FI", "is": "is-IS",
+	"pl": "pl-PL", "cz": "cs-CZ", "hu": "hu-HU", "gr": "el-GR", "ru": "ru-RU",
+	"com.tr": "tr-TR", "co.id": "id-ID", "com.vn": "vi-VN", "mx": "es-MX",
+	"com.mx": "es-MX", "com.ar": "es-AR", "cl": "es-CL", "com.co": "es-CO",
+	"jp": "ja-JP", "co.kr": "ko-KR", "com.hk": "zh-HK", "com.tw": "zh-TW",
+	"cn": "zh-CN", "ae": "ar-AE", "co.il": "he-IL",
+}
+
+// ParseLocale reads a locale tag such as the html lang attribute, "de", "de-DE"
+// or "en_IN". An empty tag gives the default locale.
+func ParseLocale(tag string) Locale {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	if tag == "" {
+		return DefaultLocale
+	}
+
+	parts := strings.Split(tag, "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) == 2 {
+			parts[i] = strings.ToUpper(parts[i])
+		}
+	}
+	tag = strings.Join(parts, "-")
+
+	locale := Locale{Tag: tag, Decimal: '.'}
+	if commaDecimalLanguages[parts[0]] && !dotDecimalTags[tag] {
+		locale.Decimal = ','
+	}
+	return locale
+}
+
+// Region returns the locale's region, e.g. "CA" for en-CA, or "" when the tag names only a language
+func (l Locale) Region() string {
+	parts := strings.Split(l.Tag, "-")
+	for _, part := range parts[1:] {
+		if len(part) == 2 {
+			return part
+		}
+	}
+	return ""
+}
+
+// LocaleFromHost returns the locale of an Airbnb country domain, e.g.
+// "www.airbnb.de" -> de-DE, "www.airbnb.co.in" -> en-IN
+func LocaleFromHost(host string) (Locale, bool) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if i := strings.Index(host, "airbnb."); i >= 0 {
+		if tag, ok := domainLocales[host[i+len("airbnb."):]]; ok {
+			return ParseLocale(tag), true
+		}
+	}
+	return DefaultLocale, false
+}
+
+// PageLocale picks the locale of a page from its html lang attribute, or from its
+// domain when the attribute is missing. A bare language such as "es" takes the
+// domain's region when the domain speaks it, so lang="es" on airbnb.mx is es-MX.
+func PageLocale(lang, pageURL string) Locale {
+	var fromHost Locale
+	hostKnown := false
+	if parsed, err := url.Parse(pageURL); err == nil {
+		fromHost, hostKnown = LocaleFromHost(parsed.Hostname())
+	}
+
+	if strings.TrimSpace(lang) == "" {
+		return fromHost
+	}
+	locale := ParseLocale(lang)
+	if hostKnown && !strings.Contains(locale.Tag, "-") && strings.HasPrefix(fromHost.Tag, locale.Tag+"-") {
+		return fromHost
+	}
+	return locale
+}
+
+// Confidence says how sure a parsed number is
+type Confidence int
+
+const (
+	// Failed means no number could be read; the reason says why
+	Failed Confidence = iota
+	// Guessed means the separators were ambiguous, e.g. "1.234", and the locale decided
+	Guessed
+	// Certain means the text could only be read one way
+	Certain
+)
+
+func (c Confidence) String() string {
+	switch c {
+	case Certain:
+		return "certain"
+	case Guessed:
+		return "guessed"
+	}
+	return "failed"
+}
+
+// ParsedNumber is a number read from page text
+type ParsedNumber struct {
+	Value      float64
+	Confidence Confidence
+	Reason     string // why the number failed or was guessed; empty when certain
+}
+
+// OK reports whether a number was read
+func (n ParsedNumber) OK() bool {
+	return n.Confidence != Failed
+}
+
+// numberKind changes how an ambiguous separator is read
+type numberKind int
+
+const (
+	kindPrice numberKind = iota
+	kindRating
+	kindCount
+)
+
+// ParsePrice reads the first amount of a displayed price, e.g. "1.234,56 €",
+// "₹ 12,34,567" or "1 234 kr". A single separator followed by three digits is
+// read as grouping, since prices are not written to three decimals.
+func (l Locale) ParsePrice(raw string) ParsedNumber {
+	return l.parse(raw, kindPrice)
+}
+
+// ParseRating reads a star rating between 0 and 5, e.g. "4,95 (123)"
+func (l Locale) ParseRating(raw string) ParsedNumber {
+	n := l.parse(raw, kindRating)
+	if n.OK() && n.Value > 5 {
+		return ParsedNumber{Confidence: Failed, Reason: fmt.Sprintf("rating %g in %q is above 5", n.Value, raw)}
+	}
+	return n
+}
+
+// ParseCount reads the first number of a count, e.g. "1.234 Bewertungen".
+// Half counts such as "1.5 baths" keep their fraction.
+func (l Locale) ParseCount(raw string) ParsedNumber {
+	return l.parse(raw, kindCount)
+}
+
+func (l Locale) parse(raw string, kind numberKind) ParsedNumber {
+	text := NormalizeDigits(raw)
+	if strings.TrimSpace(text) == "" {
+		return ParsedNumber{Confidence: Failed, Reason: "empty text"}
+	}
+	token := scanNumber(text)
+	if token == "" {
+		return ParsedNumber{Confidence: Failed, Reason: fmt.Sprintf("no digits in %q", raw)}
+	}
+
+	number, confidence, reason := l.interpret(token, kind)
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return ParsedNumber{Confidence: Failed, Reason: fmt.Sprintf("unreadable number %q in %q", token, raw)}
+	}
+	return ParsedNumber{Value: value, Confidence: confidence, Reason: reason}
+}
+
+// interpret rewrites a number token as plain digits with an optional "." decimal point
+func (l Locale) interpret(token string, kind numberKind) (string, Confidence, string) {
+	// Spaces and apostrophes only ever group digits
+	token = strings.Map(func(r rune) rune {
+		if isGroupSpace(r) || r == '\'' || r == '’' {
+			return -1
+		}
+		return r
+	}, token)
+
+	lastDot, lastComma := strings.LastIndex(token, "."), strings.LastIndex(token, ",")
+	switch {
+	case lastDot < 0 && lastComma < 0:
+		return token, Certain, ""
+
+	case lastDot >= 0 && lastComma >= 0:
+		// Both present: the last one is the decimal point
+		decimal := lastDot
+		if lastComma > lastDot {
+			decimal = lastComma
+		}
+		return stripSeparators(token[:decimal]) + "." + token[decimal+1:], Certain, ""
+	}
+
+	sep, last := byte('.'), lastDot
+	if lastComma >= 0 {
+		sep, last = ',', lastComma
+	}
+	if strings.Count(token, string(sep)) > 1 {
+		// Repeated, so it groups digits: "1.234.567", "12,34,567"
+		return stripSeparators(token), Certain, ""
+	}
+	if len(token)-last-1 != 3 {
+		// "4,95", "120.5": not a group of three, so a decimal point
+		return token[:last] + "." + token[last+1:], Certain, ""
+	}
+
+	// One separator before three digits: "1.234" is 1234 in de-DE and 1.234 in en-US
+	switch kind {
+	case kindPrice, kindCount:
+		return stripSeparators(token), Certain, ""
+	}
+	if rune(sep) == l.Decimal {
+		return token[:last] + "." + token[last+1:], Guessed,
+			fmt.Sprintf("%q read as a decimal, as %s writes it", token, l.Tag)
+	}
+	return stripSeparators(token), Guessed,
+		fmt.Sprintf("%q read as digit grouping, as %s writes it", token, l.Tag)
+}
+
+func stripSeparators(s string) string {
+	return strings.NewReplacer(".", "", ",", "").Replace(s)
+}
+
+// scanNumber returns the first number in text with its separators, e.g. "1.234,56"
+// from "1.234,56 €". A space only continues a number as a thousands group: after
+// one to three leading digits and before exactly three more.
+func scanNumber(text string) string {
+	runes := []rune(text)
+	start := -1
+	for i, r := range runes {
+		if isASCIIDigit(r) {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return ""
+	}
+
+	end := start
+	spaced := true // spaces may still group digits
+	for end < len(runes) {
+		r := runes[end]
+		switch {
+		case isASCIIDigit(r):
+			end++
+		case (r == '.' || r == ',' || r == '\'' || r == '’') && end+1 < len(runes) && isASCIIDigit(runes[end+1]):
+			spaced = spaced && (r == '\'' || r == '’')
+			end++
+		case isGroupSpace(r) && spaced && groupFollows(runes, end+1) && end-groupStart(runes, start, end) <= 3:
+			end++
+		default:
+			return string(runes[start:end])
+		}
+	}
+	return string(runes[start:end])
+}
+
+// groupFollows reports whether exactly three digits start at i
+func groupFollows(runes []rune, i int) bool {
+	for j := i; j < i+3; j++ {
+		if j >= len(runes) || !isASCIIDigit(runes[j]) {
+			return false
+		}
+	}
+	return i+3 == len(runes) || !isASCIIDigit(runes[i+3])
+}
+
+// groupStart returns where the digit group ending at end starts
+func groupStart(runes []rune, start, end int) int {
+	i := end
+	for i > start && isASCIIDigit(runes[i-1]) {
+		i--
+	}
+	return i
+}
+
+func isASCIIDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isGroupSpace(r rune) bool {
+	return r == ' ' || r == ' ' || r == ' ' || r == ' '
+}
+
+// NormalizeDigits rewrites digits of other scripts, such as Arabic-Indic "١٢٣" or
+// Devanagari "१२३", as ASCII digits, and the Arabic decimal and thousands
+// separators as "." and ","
+func NormalizeDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x80:
+			return r
+		case r == '٫':
+			return '.'
+		case r == '٬':
+			return ','
+		case unicode.Is(unicode.Nd, r):
+			return '0' + digitValue(r)
+		}
+		return r
+	}, s)
+}
+
+// digitValue returns the value of a decimal digit. Unicode encodes every script's
+// digits as runs of ten starting at zero, some of them back to back.
+func digitValue(r rune) rune {
+	zero := r
+	for unicode.Is(unicode.Nd, zero-1) {
+		zero--
+	}
+	return (r - zero) % 10
+}
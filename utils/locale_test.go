@@ -0,0 +1,71 @@
+package utils
+
+import "testing"
+
+func TestLocaleParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		locale     string
+		parse      func(Locale, string) ParsedNumber
+		raw        string
+		want       float64
+		confidence Confidence
+	}{
+		{"german price", "de-DE", Locale.ParsePrice, "1.234,56 €", 1234.56, Certain},
+		{"german grouped price", "de-DE", Locale.ParsePrice, "1.234 €", 1234, Certain},
+		{"us price", "en-US", Locale.ParsePrice, "$1,234.56 total", 1234.56, Certain},
+		{"indian grouping", "en-IN", Locale.ParsePrice, "₹ 12,34,567", 1234567, Certain},
+		{"swedish space grouping", "sv-SE", Locale.ParsePrice, "1 234 kr", 1234, Certain},
+		{"swedish no-break space grouping", "sv-SE", Locale.ParsePrice, "1 234 kr", 1234, Certain},
+		{"swiss apostrophe grouping", "de-CH", Locale.ParsePrice, "CHF 1'234.50", 1234.50, Certain},
+		{"space before a second number", "en-US", Locale.ParsePrice, "$120 2 nights", 120, Certain},
+		{"arabic-indic price", "ar-AE", Locale.ParsePrice, "١٬٢٣٤٫٥٦ د.إ", 1234.56, Certain},
+		{"devanagari price", "hi-IN", Locale.ParsePrice, "₹ १२,३४५", 12345, Certain},
+		{"no digits", "en-US", Locale.ParsePrice, "Price unavailable", 0, Failed},
+		{"empty", "en-US", Locale.ParsePrice, "", 0, Failed},
+
+		{"german rating", "de-DE", Locale.ParseRating, "4,95 (123)", 4.95, Certain},
+		{"arabic-indic rating", "ar-AE", Locale.ParseRating, "٤٫٨٧", 4.87, Certain},
+		{"ambiguous rating in a comma locale", "de-DE", Locale.ParseRating, "4,950", 4.95, Guessed},
+		{"ambiguous rating in a dot locale", "en-US", Locale.ParseRating, "4.950", 4.95, Guessed},
+		{"rating above five", "en-US", Locale.ParseRating, "49", 0, Failed},
+
+		{"german review count", "de-DE", Locale.ParseCount, "1.234 Bewertungen", 1234, Certain},
+		{"us review count", "en-US", Locale.ParseCount, "1,234", 1234, Certain},
+		{"half bath", "en-US", Locale.ParseCount, "1.5 baths", 1.5, Certain},
+		{"german half bath", "de-DE", Locale.ParseCount, "1,5 Bäder", 1.5, Certain},
+		{"arabic-indic guests", "ar-AE", Locale.ParseCount, "٦ ضيوف", 6, Certain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.parse(ParseLocale(tt.locale), tt.raw)
+			if got.Value != tt.want || got.Confidence != tt.confidence {
+				t.Errorf("%q in %s = %v (%s, %q), want %v (%s)",
+					tt.raw, tt.locale, got.Value, got.Confidence, got.Reason, tt.want, tt.confidence)
+			}
+			if (got.Confidence == Certain) != (got.Reason == "") {
+				t.Errorf("%q: confidence %s with reason %q", tt.raw, got.Confidence, got.Reason)
+			}
+		})
+	}
+}
+
+func TestPageLocale(t *testing.T) {
+	tests := []struct {
+		lang, url string
+		want      Locale
+	}{
+		{"", "https://www.airbnb.de/s/Berlin/homes", Locale{Tag: "de-DE", Decimal: ','}},
+		{"es", "https://www.airbnb.mx/s/CDMX/homes", Locale{Tag: "es-MX", Decimal: '.'}},
+		{"en_IN", "https://www.airbnb.com/", Locale{Tag: "en-IN", Decimal: '.'}},
+		{"fr", "https://www.airbnb.com/", Locale{Tag: "fr", Decimal: ','}},
+		{"", "https://example.com/", DefaultLocale},
+	}
+
+	for _, tt := range tests {
+		if got := PageLocale(tt.lang, tt.url); got != tt.want {
+			t.Errorf("PageLocale(%q, %q) = %+v, want %+v", tt.lang, tt.url, got, tt.want)
+		}
+	}
+}
@@ -7,7 +7,8 @@ import (
 )
 
 // NormalizePrice extracts numeric price from strings like "$120", "£150 AUD", "$1,234"
-// Returns the price as float64 or 0.0 if parsing fails
+// Returns the price as float64 or 0.0 if parsing fails. DetectCurrency reads the currency.
+// It assumes US formatting; Locale.ParsePrice reads other locales and says why it failed.
 func NormalizePrice(raw string) float64 {
 	// Extract all digits including decimal point
 	re := regexp.MustCompile(`[\d,]+\.?\d*`)
@@ -29,7 +30,7 @@ func NormalizePrice(raw string) float64 {
 }
 
 // NormalizeRating extracts numeric rating from strings like "4.95 (123 reviews)", "4.8"
-// Returns the rating as float64 or 0.0 if parsing fails
+// Returns the rating as float64 or 0.0 if parsing fails. Locale.ParseRating reads "4,95".
 func NormalizeRating(raw string) float64 {
 	// Extract first decimal number
 	re := regexp.MustCompile(`\d+\.?\d*`)
@@ -52,6 +53,13 @@ func NormalizeRating(raw string) float64 {
 	return rating
 }
 
+// IsStayTotal reports whether a displayed price is for the whole stay rather than
+// per night, e.g. "$450 total" or "$450 for 2 nights"
+func IsStayTotal(raw string) bool {
+	re := regexp.MustCompile(`(?i)\btotal\b|\bfor \d+ nights\b`)
+	return re.MatchString(raw)
+}
+
 // ExtractNumber extracts first integer from string
 // Used for bedrooms, bathrooms, guests (e.g., "3 bedrooms" -> 3)
 func ExtractNumber(raw string) int {